@@ -0,0 +1,327 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applychanges
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/cli"
+	"m4o.io/pbf/v2/cmd/pbf/internal/sorted"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/osmxml"
+)
+
+// batchSize is the number of entities sent to the encoder at a time.
+const batchSize = 8000
+
+var out *os.File
+
+func init() { //nolint:gochecknoinits
+	cli.RootCmd.AddCommand(applyChangesCmd)
+
+	flags := applyChangesCmd.Flags()
+	flags.VarP(cli.NewWriterValue(os.Stdout, &out, "<OSM destination>"), "out", "o", "output OSM file")
+	flags.Int64P("sequence-number", "n", -1,
+		"replication sequence number of the output (default the base's plus the number of change files)")
+	flags.StringP("timestamp", "t", "",
+		"RFC 3339 replication timestamp of the output (default the newest of the base and the changes)")
+	flags.Uint16P("cpu", "c", pbf.DefaultNCpu(), "number of CPUs to use for decoding")
+}
+
+var applyChangesCmd = &cobra.Command{
+	Use:   "apply-changes <OSM source> <OSM change>...",
+	Short: "Apply OSM change files to an OSM file",
+	Long: `Apply OSM change files, .osc or .osc.gz, in order, to an OSM file sorted by
+type, then ID, writing a sorted OSM file.  For example:
+
+    pbf apply-changes base.osm.pbf 001.osc.gz 002.osc.gz -o out.osm.pbf
+
+The locations of ways, if the base has them, are not carried over.`,
+	Args: cobra.MinimumNArgs(2), //nolint:mnd
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+
+		var (
+			cfg config
+			err error
+		)
+
+		if cfg.sequenceNumber, err = flags.GetInt64("sequence-number"); err != nil {
+			log.Fatal(err)
+		}
+
+		ts, err := flags.GetString("timestamp")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if ts != "" {
+			if cfg.timestamp, err = time.Parse(time.RFC3339, ts); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		ncpu, err := flags.GetUint16("cpu")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		files := make([]*os.File, len(args))
+		for i, name := range args {
+			if files[i], err = os.Open(name); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		changes := make([]io.Reader, len(files)-1)
+		for i, f := range files[1:] {
+			changes[i] = f
+		}
+
+		if err = runApplyChanges(files[0], changes, out, cfg, pbf.WithNCpus(ncpu)); err != nil {
+			log.Fatal(err)
+		}
+
+		for _, f := range files {
+			if err = f.Close(); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if err = out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// config holds the replication details of the output.  A negative sequence
+// number, or a zero timestamp, is derived from the base and the changes.
+type config struct {
+	sequenceNumber int64
+	timestamp      time.Time
+}
+
+// runApplyChanges applies the changes, in order, to base, which must be
+// sorted by type, then ID, and writes the result, sorted, to out.  Since the
+// result is written as it is merged, in order, the encoder keeps that order
+// rather than sorting it again.  The locations of the ways of base are kept,
+// but change files have none for theirs.
+func runApplyChanges(base io.Reader, changes []io.Reader, out io.Writer, cfg config, opts ...pbf.DecoderOption) error {
+	merged, newest, err := readChanges(changes)
+	if err != nil {
+		return err
+	}
+
+	d, err := pbf.NewDecoder(context.Background(), base, append(opts, pbf.WithOrdered())...)
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	if cfg.sequenceNumber < 0 {
+		cfg.sequenceNumber = d.Header.OsmosisReplicationSequenceNumber + int64(len(changes))
+	}
+
+	if cfg.timestamp.IsZero() {
+		cfg.timestamp = d.Header.OsmosisReplicationTimestamp
+		if newest.After(cfg.timestamp) {
+			cfg.timestamp = newest
+		}
+	}
+
+	encOpts := []pbf.EncoderOption{
+		pbf.WithInputOrder(),
+		pbf.WithOptionalFeatures(pbf.SortTypeThenID),
+		pbf.WithWritingProgram("pbf"),
+		pbf.WithSource(d.Header.Source),
+		pbf.WithOsmosisReplicationTimestamp(cfg.timestamp),
+		pbf.WithOsmosisReplicationSequenceNumber(cfg.sequenceNumber),
+		pbf.WithOsmosisReplicationBaseURL(d.Header.OsmosisReplicationBaseURL),
+	}
+
+	if slices.Contains(d.Header.RequiredFeatures, pbf.LocationsOnWays) {
+		encOpts = append(encOpts, pbf.WithLocationsOnWays())
+
+		if n := changedWays(merged); n > 0 {
+			slog.Warn("writing the ways of the changes without locations", "count", n)
+		}
+	}
+
+	e, err := pbf.NewEncoder(out, encOpts...)
+	if err != nil {
+		return err
+	}
+
+	if err := apply(d.All(), merged, e); err != nil {
+		return errors.Join(err, e.Close())
+	}
+
+	return e.Close()
+}
+
+// readChanges reads the changes of each reader, in order, and returns them
+// sorted by type, then ID, keeping only the change with the highest version
+// of each entity, or the last one read if they share a version.  The newest
+// timestamp of the changes is also returned.
+func readChanges(rdrs []io.Reader) ([]model.Change, time.Time, error) {
+	var (
+		changes []model.Change
+		newest  time.Time
+	)
+
+	for _, rdr := range rdrs {
+		d, err := osmxml.NewChangeDecoder(rdr)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		for c, err := range d.All() {
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+
+			if info := c.Entity.GetInfo(); info != nil && info.Timestamp.After(newest) {
+				newest = info.Timestamp
+			}
+
+			changes = append(changes, c)
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b model.Change) int {
+		return model.CompareVersions(a.Entity, b.Entity)
+	})
+
+	merged := changes[:0]
+
+	for _, c := range changes {
+		if n := len(merged); n > 0 && model.Compare(merged[n-1].Entity, c.Entity) == 0 {
+			merged[n-1] = c
+		} else {
+			merged = append(merged, c)
+		}
+	}
+
+	return merged, newest, nil
+}
+
+// changedWays returns the number of ways that the changes create or modify.
+func changedWays(changes []model.Change) int {
+	n := 0
+
+	for _, c := range changes {
+		if c.Action != model.DELETE && model.TypeOf(c.Entity) == model.WAY {
+			n++
+		}
+	}
+
+	return n
+}
+
+// apply merges the base entities with the changes, both sorted by type, then
+// ID, and encodes the result with e.
+func apply(base iter.Seq2[model.Entity, error], changes []model.Change, e *pbf.Encoder) error {
+	batch := make([]model.Entity, 0, batchSize)
+
+	emit := func(entity model.Entity) error {
+		batch = append(batch, entity)
+		if len(batch) < batchSize {
+			return nil
+		}
+
+		err := e.EncodeBatch(batch)
+		batch = make([]model.Entity, 0, batchSize)
+
+		return err
+	}
+
+	// emitChanges emits the changes preceding entity, or all of them if
+	// entity is nil, and reports whether the next one replaces entity
+	emitChanges := func(entity model.Entity) (bool, error) {
+		for len(changes) > 0 {
+			c := changes[0]
+
+			order := -1
+			if entity != nil {
+				order = model.Compare(c.Entity, entity)
+			}
+
+			if order > 0 {
+				return false, nil
+			}
+
+			changes = changes[1:]
+
+			if c.Action != model.DELETE {
+				if err := emit(c.Entity); err != nil {
+					return false, err
+				}
+			}
+
+			if order == 0 {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	c := sorted.NewCursor("base", base)
+	defer c.Stop()
+
+	for {
+		if err := c.Advance(); err != nil {
+			return err
+		}
+
+		entity := c.Cur
+		if entity == nil {
+			break
+		}
+
+		replaced, err := emitChanges(entity)
+		if err != nil {
+			return err
+		}
+
+		if !replaced {
+			if err := emit(entity); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := emitChanges(nil); err != nil {
+		return err
+	}
+
+	if len(batch) > 0 {
+		return e.EncodeBatch(batch)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applychanges
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/internal/pbftest"
+	"m4o.io/pbf/v2/cmd/pbf/internal/sorted"
+	"m4o.io/pbf/v2/model"
+)
+
+// sortedSample returns the sample, sorted by type, then ID, and its entities.
+func sortedSample(t *testing.T) ([]byte, []model.Entity) {
+	t.Helper()
+
+	base, err := io.ReadAll(pbftest.EncodeSorted(t, pbftest.SampleEntities(t), pbf.WithOsmosisReplicationSequenceNumber(41)))
+	require.NoError(t, err)
+
+	return base, decodeAll(t, bytes.NewReader(base)).entities
+}
+
+type decoded struct {
+	header   model.Header
+	entities []model.Entity
+}
+
+func decodeAll(t *testing.T, in io.Reader) decoded {
+	t.Helper()
+
+	d, err := pbf.NewDecoder(context.Background(), in, pbf.WithOrdered())
+	require.NoError(t, err)
+	defer d.Close()
+
+	return decoded{header: d.Header, entities: pbftest.DecodeAll(t, d)}
+}
+
+func find(entities []model.Entity, typ model.EntityType, id model.ID) model.Entity {
+	for _, e := range entities {
+		if model.TypeOf(e) == typ && e.GetID() == id {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func TestRunApplyChanges(t *testing.T) {
+	base, entities := sortedSample(t)
+
+	node := entities[0].(*model.Node)
+
+	var way *model.Way
+	for _, e := range entities {
+		if w, ok := e.(*model.Way); ok {
+			way = w
+
+			break
+		}
+	}
+
+	require.NotNil(t, way)
+
+	first := fmt.Sprintf(`<osmChange version="0.6">
+  <modify>
+    <node id="%d" version="%d" timestamp="2030-01-02T03:04:05Z" lat="51.5" lon="-0.1">
+      <tag k="amenity" v="bench"/>
+    </node>
+  </modify>
+  <create>
+    <node id="99999999999" version="1" lat="51.6" lon="-0.2"/>
+    <node id="99999999998" version="1" lat="51.6" lon="-0.2"/>
+  </create>
+</osmChange>`, node.ID, node.Info.Version+1)
+
+	second := fmt.Sprintf(`<osmChange version="0.6">
+  <delete>
+    <way id="%d" version="%d"/>
+    <node id="99999999998" version="2"/>
+  </delete>
+</osmChange>`, way.ID, way.Info.Version+1)
+
+	var out bytes.Buffer
+	require.NoError(t, runApplyChanges(bytes.NewReader(base),
+		[]io.Reader{strings.NewReader(first), strings.NewReader(second)}, &out, config{sequenceNumber: -1}))
+
+	result := decodeAll(t, &out)
+
+	assert.Equal(t, int64(43), result.header.OsmosisReplicationSequenceNumber)
+	assert.Equal(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), result.header.OsmosisReplicationTimestamp.UTC())
+	assert.Contains(t, result.header.OptionalFeatures, "Sort.Type_then_ID")
+
+	// one node created and one way deleted
+	assert.Len(t, result.entities, len(entities))
+	assert.True(t, slices.IsSortedFunc(result.entities, model.Compare))
+
+	modified := find(result.entities, model.NODE, node.ID)
+	require.NotNil(t, modified)
+	assert.Equal(t, map[string]string{"amenity": "bench"}, modified.GetTags())
+	assert.Equal(t, node.Info.Version+1, modified.GetInfo().Version)
+
+	assert.NotNil(t, find(result.entities, model.NODE, 99999999999))
+	assert.Nil(t, find(result.entities, model.NODE, 99999999998))
+	assert.Nil(t, find(result.entities, model.WAY, way.ID))
+}
+
+func TestRunApplyChangesKeepsLocationsOnWays(t *testing.T) {
+	way := &model.Way{
+		ID: 1, Tags: map[string]string{}, Info: &model.Info{Version: 1, Visible: true},
+		NodeIDs: []model.ID{1, 2}, Lats: []model.Degrees{51.5, 51.6}, Lons: []model.Degrees{-0.1, -0.2},
+	}
+	base := pbftest.EncodeSorted(t, []model.Entity{way}, pbf.WithLocationsOnWays())
+
+	var out bytes.Buffer
+	require.NoError(t, runApplyChanges(base,
+		[]io.Reader{strings.NewReader(`<osmChange version="0.6"/>`)}, &out, config{sequenceNumber: -1}))
+
+	result := decodeAll(t, &out)
+
+	assert.Contains(t, result.header.RequiredFeatures, pbf.LocationsOnWays)
+	require.Len(t, result.entities, 1)
+	assert.Equal(t, way.Lats, result.entities[0].(*model.Way).Lats)
+	assert.Equal(t, way.Lons, result.entities[0].(*model.Way).Lons)
+}
+
+func TestRunApplyChangesOverridesReplication(t *testing.T) {
+	base, _ := sortedSample(t)
+	ts := time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var out bytes.Buffer
+	require.NoError(t, runApplyChanges(bytes.NewReader(base),
+		[]io.Reader{strings.NewReader(`<osmChange version="0.6"/>`)}, &out, config{sequenceNumber: 7, timestamp: ts}))
+
+	result := decodeAll(t, &out)
+
+	assert.Equal(t, int64(7), result.header.OsmosisReplicationSequenceNumber)
+	assert.Equal(t, ts, result.header.OsmosisReplicationTimestamp.UTC())
+}
+
+func TestRunApplyChangesRejectsUnsortedBase(t *testing.T) {
+	f, err := os.Open(pbftest.SamplePath())
+	require.NoError(t, err)
+	defer f.Close()
+
+	err = runApplyChanges(f, nil, io.Discard, config{sequenceNumber: -1})
+	assert.ErrorIs(t, err, sorted.ErrUnsorted)
+}
@@ -0,0 +1,243 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/cli"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/o5m"
+	"m4o.io/pbf/v2/opl"
+	"m4o.io/pbf/v2/osmxml"
+)
+
+// Output formats.
+const (
+	PBF = "pbf"
+	XML = "osm"
+	OPL = "opl"
+	O5M = "o5m"
+)
+
+// ErrUnsupportedFormat is returned for an unknown output format.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+var out *os.File
+
+func init() { //nolint:gochecknoinits
+	cli.RootCmd.AddCommand(catCmd)
+
+	flags := catCmd.Flags()
+	flags.VarP(cli.NewWriterValue(os.Stdout, &out, "<OSM destination>"), "out", "o", "output OSM file")
+	flags.StringP("format", "f", "", "output format, pbf, osm, opl or o5m (default from the output file name, else pbf)")
+	flags.Uint16P("cpu", "c", pbf.DefaultNCpu(), "number of CPUs to use for decoding")
+}
+
+var catCmd = &cobra.Command{
+	Use:   "cat [<OSM source>]",
+	Short: "Convert OSM files between formats",
+	Long: `Copy the entities of an OSM file, read from stdin if no file is given, to
+another in the chosen format.  The input format, PBF, XML or o5m, is detected
+from its content.  For example:
+
+    pbf cat in.osm.pbf -o out.osm
+    pbf cat -f opl in.osm.pbf | grep amenity=bench`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+
+		format, err := flags.GetString("format")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if format == "" {
+			format = formatOf(out.Name())
+		}
+
+		ncpu, err := flags.GetUint16("cpu")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		in := os.Stdin
+		if len(args) > 0 {
+			if in, err = os.Open(args[0]); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if err = runCat(in, out, format, pbf.WithNCpus(ncpu)); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = in.Close(); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// formatOf returns the format implied by the extension of the file name.
+func formatOf(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".osm"):
+		return XML
+	case strings.HasSuffix(name, ".opl"):
+		return OPL
+	case strings.HasSuffix(name, ".o5m"):
+		return O5M
+	default:
+		return PBF
+	}
+}
+
+// decoder is satisfied by the PBF, XML and o5m decoders.
+type decoder interface {
+	Decode() ([]model.Entity, error)
+}
+
+// encoder is satisfied by the PBF, XML, OPL and o5m encoders.
+type encoder interface {
+	EncodeBatch(entities []model.Entity) error
+	Close() error
+}
+
+// runCat copies the entities of in, in order, to out in the given format.
+// PBF output is batched by type, so it only keeps the order of input that
+// declares Sort.Type_then_ID.
+func runCat(in io.Reader, out io.Writer, format string, opts ...pbf.DecoderOption) error {
+	br := bufio.NewReader(in)
+
+	var (
+		d   decoder
+		hdr model.Header
+	)
+
+	switch {
+	case isXML(br):
+		xd, err := osmxml.NewDecoder(br)
+		if err != nil {
+			return err
+		}
+
+		d, hdr = xd, xd.Header
+	case isO5M(br):
+		od, err := o5m.NewDecoder(br)
+		if err != nil {
+			return err
+		}
+
+		d, hdr = od, od.Header
+	default:
+		pd, err := pbf.NewDecoder(context.Background(), br, append(opts, pbf.WithOrdered())...)
+		if err != nil {
+			return err
+		}
+		defer pd.Close()
+
+		d, hdr = pd, pd.Header
+	}
+
+	hdr.WritingProgram = "pbf"
+
+	e, err := newEncoder(out, format, hdr)
+	if err != nil {
+		return err
+	}
+
+	for {
+		entities, err := d.Decode()
+		switch {
+		case errors.Is(err, io.EOF):
+			return e.Close()
+		case err != nil:
+			return errors.Join(err, e.Close())
+		}
+
+		if err := e.EncodeBatch(entities); err != nil {
+			return errors.Join(err, e.Close())
+		}
+	}
+}
+
+// newEncoder returns an encoder of the format that writes to out.
+func newEncoder(out io.Writer, format string, hdr model.Header) (encoder, error) {
+	switch format {
+	case PBF:
+		opts := []pbf.EncoderOption{
+			pbf.WithStreaming(hdr.BoundingBox),
+			pbf.WithOptionalFeatures(cli.WithoutSortFeatures(hdr.OptionalFeatures)...),
+			pbf.WithWritingProgram(hdr.WritingProgram),
+			pbf.WithSource(hdr.Source),
+			pbf.WithOsmosisReplicationTimestamp(hdr.OsmosisReplicationTimestamp),
+			pbf.WithOsmosisReplicationSequenceNumber(hdr.OsmosisReplicationSequenceNumber),
+			pbf.WithOsmosisReplicationBaseURL(hdr.OsmosisReplicationBaseURL),
+		}
+
+		// the encoder batches entities by type as they arrive, so only keeps
+		// the order of sorted input, which it is told to keep
+		if slices.Contains(hdr.OptionalFeatures, pbf.SortTypeThenID) {
+			opts = append(opts, pbf.WithSorted())
+		}
+
+		if slices.Contains(hdr.RequiredFeatures, pbf.LocationsOnWays) {
+			opts = append(opts, pbf.WithLocationsOnWays())
+		}
+
+		return pbf.NewEncoder(out, opts...)
+	case XML:
+		return osmxml.NewEncoder(out, hdr)
+	case OPL:
+		return opl.NewEncoder(out), nil
+	case O5M:
+		return o5m.NewEncoder(out, hdr)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// isO5M reports whether the buffered input starts with the reset and header
+// datasets of o5m.
+func isO5M(br *bufio.Reader) bool {
+	b, _ := br.Peek(2) //nolint:mnd
+
+	return bytes.Equal(b, []byte{0xff, 0xe0})
+}
+
+// isXML reports whether the buffered input starts with an XML element rather
+// than the length of a PBF blob header.
+func isXML(br *bufio.Reader) bool {
+	b, _ := br.Peek(64)
+	b = bytes.TrimLeft(b, " \t\r\n\ufeff")
+
+	return len(b) > 0 && b[0] == '<'
+}
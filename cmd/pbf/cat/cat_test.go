@@ -0,0 +1,227 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cat
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/internal/pbftest"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/o5m"
+	"m4o.io/pbf/v2/opl"
+	"m4o.io/pbf/v2/osmxml"
+)
+
+func TestRunCatRoundTrip(t *testing.T) {
+	f, err := os.Open(pbftest.SamplePath())
+	require.NoError(t, err)
+	defer f.Close()
+
+	var xml bytes.Buffer
+	require.NoError(t, runCat(f, &xml, XML))
+
+	var out bytes.Buffer
+	require.NoError(t, runCat(bytes.NewReader(xml.Bytes()), &out, PBF))
+
+	xd, err := osmxml.NewDecoder(bytes.NewReader(xml.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "pbf", xd.Header.WritingProgram)
+
+	want := pbftest.DecodeAll(t, xd)
+	assert.Len(t, want, 339)
+
+	pd, err := pbf.NewDecoder(context.Background(), &out, pbf.WithOrdered())
+	require.NoError(t, err)
+	defer pd.Close()
+
+	assert.Equal(t, want, pbftest.DecodeAll(t, pd))
+}
+
+func TestRunCatRoundTripWithoutMetadata(t *testing.T) {
+	const osm = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="pbf">
+  <node id="1" lat="51.5" lon="-0.1"></node>
+  <node id="2" lat="51.6" lon="-0.2">
+    <tag k="amenity" v="bench"></tag>
+  </node>
+  <way id="1">
+    <nd ref="1"></nd>
+    <nd ref="2"></nd>
+  </way>
+  <relation id="1">
+    <member type="way" ref="1" role="outer"></member>
+  </relation>
+</osm>
+`
+
+	var pbfOut bytes.Buffer
+	require.NoError(t, runCat(strings.NewReader(osm), &pbfOut, PBF))
+
+	var xmlOut bytes.Buffer
+	require.NoError(t, runCat(&pbfOut, &xmlOut, XML))
+
+	// PBF output groups unsorted input by type, so the order is not compared
+	want, err := osmxml.NewDecoder(strings.NewReader(osm))
+	require.NoError(t, err)
+
+	got, err := osmxml.NewDecoder(&xmlOut)
+	require.NoError(t, err)
+
+	assert.True(t, got.Header.OsmosisReplicationTimestamp.IsZero())
+	assert.ElementsMatch(t, pbftest.DecodeAll(t, want), pbftest.DecodeAll(t, got))
+	assert.NotContains(t, xmlOut.String(), "timestamp=")
+}
+
+func TestRunCatKeepsSortedPBF(t *testing.T) {
+	// several blocks of each type, as the encoder batches 8,000 entities
+	var entities []model.Entity
+	for i := range 20_500 {
+		entities = append(entities, &model.Node{ID: model.ID(i + 1), Info: &model.Info{Visible: true}})
+	}
+
+	for i := range 9_000 {
+		entities = append(entities, &model.Way{ID: model.ID(i + 1), NodeIDs: []model.ID{1, 2}, Info: &model.Info{Visible: true}})
+	}
+
+	var in bytes.Buffer
+
+	e, err := pbf.NewEncoder(&in, pbf.WithSorted(), pbf.WithStreaming(nil))
+	require.NoError(t, err)
+	require.NoError(t, e.EncodeBatch(entities))
+	require.NoError(t, e.Close())
+
+	var out bytes.Buffer
+	require.NoError(t, runCat(&in, &out, PBF))
+
+	d, err := pbf.NewDecoder(context.Background(), &out, pbf.WithOrdered())
+	require.NoError(t, err)
+	defer d.Close()
+
+	assert.Equal(t, []string{pbf.SortTypeThenID}, d.Header.OptionalFeatures)
+
+	got := pbftest.DecodeAll(t, d)
+	require.Len(t, got, len(entities))
+
+	for i, e := range got {
+		if reflect.TypeOf(e) != reflect.TypeOf(entities[i]) || e.GetID() != entities[i].GetID() {
+			t.Fatalf("entity %d is %T %d, want %T %d", i, e, e.GetID(), entities[i], entities[i].GetID())
+		}
+	}
+}
+
+func TestRunCatDropsSortOfUnsortedPBF(t *testing.T) {
+	var in bytes.Buffer
+
+	e, err := pbf.NewEncoder(&in, pbf.WithStreaming(nil), pbf.WithOptionalFeatures("Sort.Geographic"))
+	require.NoError(t, err)
+	require.NoError(t, e.Encode(&model.Node{ID: 1, Info: &model.Info{Visible: true}}))
+	require.NoError(t, e.Close())
+
+	var out bytes.Buffer
+	require.NoError(t, runCat(&in, &out, PBF))
+
+	d, err := pbf.NewDecoder(context.Background(), &out)
+	require.NoError(t, err)
+	defer d.Close()
+
+	assert.Empty(t, d.Header.OptionalFeatures)
+}
+
+func TestRunCatOPL(t *testing.T) {
+	f, err := os.Open("../../../testdata/sample.osm")
+	require.NoError(t, err)
+	defer f.Close()
+
+	var out bytes.Buffer
+	require.NoError(t, runCat(f, &out, OPL))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lines, 339)
+
+	entities := pbftest.DecodeAll(t, opl.NewDecoder(&out))
+	assert.Len(t, entities, 339)
+}
+
+func TestRunCatO5M(t *testing.T) {
+	f, err := os.Open("../../../testdata/sample.osm")
+	require.NoError(t, err)
+	defer f.Close()
+
+	var o5mOut bytes.Buffer
+	require.NoError(t, runCat(f, &o5mOut, O5M))
+
+	var xmlOut bytes.Buffer
+	require.NoError(t, runCat(bytes.NewReader(o5mOut.Bytes()), &xmlOut, XML))
+
+	want, err := os.ReadFile("../../../testdata/sample.osm")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), xmlOut.String())
+}
+
+func TestRunCatO5MWithoutVersionsToPBF(t *testing.T) {
+	entities := []model.Entity{
+		&model.Node{ID: 1, Lat: 51.5, Lon: -0.1, Tags: map[string]string{"amenity": "bench"}},
+		&model.Node{ID: 2, Lat: 51.6, Lon: -0.2, Tags: map[string]string{}},
+		&model.Way{ID: 1, NodeIDs: []model.ID{1, 2}, Tags: map[string]string{}},
+		&model.Relation{ID: 1, Members: []model.Member{{ID: 1, Type: model.WAY, Role: "outer"}}, Tags: map[string]string{}},
+	}
+
+	var in bytes.Buffer
+
+	e, err := o5m.NewEncoder(&in, model.Header{})
+	require.NoError(t, err)
+	require.NoError(t, e.EncodeBatch(entities))
+	require.NoError(t, e.Close())
+
+	var out bytes.Buffer
+	require.NoError(t, runCat(&in, &out, PBF))
+
+	d, err := pbf.NewDecoder(context.Background(), &out)
+	require.NoError(t, err)
+	defer d.Close()
+
+	got := pbftest.DecodeAll(t, d)
+	require.Len(t, got, len(entities))
+
+	for _, e := range got {
+		assert.Zero(t, e.GetInfo().Version)
+		assert.True(t, e.GetInfo().Timestamp.IsZero())
+	}
+}
+
+func TestRunCatUnsupportedFormat(t *testing.T) {
+	f, err := os.Open("../../../testdata/sample.osm")
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.ErrorIs(t, runCat(f, &bytes.Buffer{}, "shp"), ErrUnsupportedFormat)
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, XML, formatOf("out.osm"))
+	assert.Equal(t, OPL, formatOf("out.opl"))
+	assert.Equal(t, O5M, formatOf("out.o5m"))
+	assert.Equal(t, PBF, formatOf("out.osm.pbf"))
+	assert.Equal(t, PBF, formatOf("/dev/stdout"))
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkrefs
+
+import "m4o.io/pbf/v2/model"
+
+// chunkBits is the log2 of the number of IDs held by a chunk of a bitset.
+const chunkBits = 16
+
+// chunk holds a bit for each of 1 << chunkBits consecutive IDs.
+type chunk [1 << chunkBits / 64]uint64
+
+// bitset is a set of IDs, stored as bits in chunks that are only allocated
+// once one of their IDs is added, so that the sparse, but clustered, IDs of
+// OSM files are held compactly.
+type bitset map[int64]*chunk
+
+// add adds id to the set and reports whether it was already there.
+func (s bitset) add(id model.ID) bool {
+	c, ok := s[int64(id)>>chunkBits]
+	if !ok {
+		c = &chunk{}
+		s[int64(id)>>chunkBits] = c
+	}
+
+	word, bit := split(id)
+	had := c[word]&bit != 0
+	c[word] |= bit
+
+	return had
+}
+
+// has reports whether id is in the set.
+func (s bitset) has(id model.ID) bool {
+	c, ok := s[int64(id)>>chunkBits]
+	if !ok {
+		return false
+	}
+
+	word, bit := split(id)
+
+	return c[word]&bit != 0
+}
+
+// split returns the word within its chunk, and the bit within that word, of
+// id.
+func split(id model.ID) (int, uint64) {
+	i := int64(id) & (1<<chunkBits - 1)
+
+	return int(i / 64), 1 << (i % 64)
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkrefs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/cli"
+	"m4o.io/pbf/v2/model"
+)
+
+var (
+	in  *os.File
+	out io.Writer = os.Stdout
+)
+
+func init() { //nolint:gochecknoinits
+	cli.RootCmd.AddCommand(checkRefsCmd)
+
+	flags := checkRefsCmd.Flags()
+	flags.VarP(cli.NewReaderValue(os.Stdin, &in, "<OSM source>"), "in", "i", "input OSM file")
+	flags.BoolP("show-ids", "v", false, "list the IDs of the missing, duplicate and out of order entities")
+	flags.Uint16P("cpu", "c", pbf.DefaultNCpu(), "number of CPUs to use for decoding")
+	flags.BoolP("silent", "s", false, "silence progress bar")
+}
+
+var checkRefsCmd = &cobra.Command{
+	Use:   "check-refs",
+	Short: "Check the referential integrity of an OSM file",
+	Long: `Check that the nodes of ways and the members of relations are in an OSM file,
+and that its IDs are neither duplicated nor out of order.  The nodes must come
+before the ways, and the nodes and ways before the relations, as they do in
+sorted files; relations can reference relations anywhere in the file.  The
+exit status is 1 if any problem is found.  For example:
+
+    pbf check-refs -v -i extract.osm.pbf`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		flags := cmd.Flags()
+
+		silent, err := flags.GetBool("silent")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var win io.ReadCloser
+		if silent {
+			win = in
+		} else {
+			win, err = cli.WrapInputFile(in)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		showIDs, err := flags.GetBool("show-ids")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ncpu, err := flags.GetUint16("cpu")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		r, err := runCheckRefs(win, out, showIDs, pbf.WithNCpus(ncpu))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err = win.Close(); err != nil {
+			log.Fatal(err)
+		}
+
+		r.render(out)
+
+		if !r.ok() {
+			os.Exit(1)
+		}
+	},
+}
+
+// report holds the counts of the entities checked and of the problems found.
+type report struct {
+	nodes, ways, relations int64
+
+	duplicates int64 // entities whose type and ID were seen before
+	unordered  int64 // entities that precede the entity before them
+
+	waysMissingNodes int64 // ways with at least one missing node
+	missingNodes     int64 // references to missing nodes by ways
+	relationsMissing int64 // relations with at least one missing member
+	missingMembers   int64 // references to missing members by relations
+}
+
+// ok reports whether no problem was found.
+func (r *report) ok() bool {
+	return r.duplicates == 0 && r.unordered == 0 && r.missingNodes == 0 && r.missingMembers == 0
+}
+
+func (r *report) render(w io.Writer) {
+	fmt.Fprintf(w, "Nodes: %s\n", humanize.Comma(r.nodes))
+	fmt.Fprintf(w, "Ways: %s\n", humanize.Comma(r.ways))
+	fmt.Fprintf(w, "Relations: %s\n", humanize.Comma(r.relations))
+	fmt.Fprintf(w, "DuplicateIDs: %s\n", humanize.Comma(r.duplicates))
+	fmt.Fprintf(w, "OutOfOrderIDs: %s\n", humanize.Comma(r.unordered))
+	fmt.Fprintf(w, "WaysWithMissingNodes: %s (%s missing node references)\n",
+		humanize.Comma(r.waysMissingNodes), humanize.Comma(r.missingNodes))
+	fmt.Fprintf(w, "RelationsWithMissingMembers: %s (%s missing member references)\n",
+		humanize.Comma(r.relationsMissing), humanize.Comma(r.missingMembers))
+}
+
+// checker checks the entities of an OSM file as they are decoded.
+type checker struct {
+	report
+
+	ids     [3]bitset    // the IDs seen, by type
+	broken  bitset       // the relations found with missing members
+	pending []membership // the relation members to be checked at the end
+	prev    model.Entity
+
+	listing io.Writer // where IDs are listed, if they are
+}
+
+// membership is a relation member, and the relation that references it.
+type membership struct {
+	relation model.ID
+	member   model.ID
+}
+
+// runCheckRefs checks the entities of in, listing the IDs of the entities at
+// fault to out if showIDs is set.
+func runCheckRefs(in io.Reader, out io.Writer, showIDs bool, opts ...pbf.DecoderOption) (*report, error) {
+	d, err := pbf.NewDecoder(context.Background(), in, append(opts, pbf.WithOrdered())...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.Close()
+
+	c := &checker{ids: [3]bitset{{}, {}, {}}, broken: bitset{}}
+	if showIDs {
+		c.listing = out
+	}
+
+	for e, err := range d.All() {
+		if err != nil {
+			return nil, err
+		}
+
+		c.check(e)
+	}
+
+	c.checkPending()
+
+	return &c.report, nil
+}
+
+// check checks the order and ID of e and, for ways and relations, the
+// entities it references that have been seen.
+func (c *checker) check(e model.Entity) {
+	typ := model.TypeOf(e)
+
+	if c.prev != nil && model.Compare(c.prev, e) > 0 {
+		c.unordered++
+		c.list("unordered %c%d\n", typeChars[typ], e.GetID())
+	}
+
+	c.prev = e
+
+	if c.ids[typ].add(e.GetID()) {
+		c.duplicates++
+		c.list("duplicate %c%d\n", typeChars[typ], e.GetID())
+	}
+
+	switch e := e.(type) {
+	case *model.Node:
+		c.nodes++
+	case *model.Way:
+		c.ways++
+
+		missing := false
+
+		for _, id := range e.NodeIDs {
+			if !c.ids[model.NODE].has(id) {
+				missing = true
+				c.missingNodes++
+				c.list("n%d in w%d\n", id, e.ID)
+			}
+		}
+
+		if missing {
+			c.waysMissingNodes++
+		}
+	case *model.Relation:
+		c.relations++
+
+		for _, m := range e.Members {
+			if m.Type == model.RELATION {
+				c.pending = append(c.pending, membership{relation: e.ID, member: m.ID})
+			} else if !c.ids[m.Type].has(m.ID) {
+				c.missingMember(e.ID, m.Type, m.ID)
+			}
+		}
+	}
+}
+
+// checkPending checks the relations referenced by relations, once all the
+// relations have been seen.
+func (c *checker) checkPending() {
+	for _, p := range c.pending {
+		if !c.ids[model.RELATION].has(p.member) {
+			c.missingMember(p.relation, model.RELATION, p.member)
+		}
+	}
+
+	c.pending = nil
+}
+
+// missingMember records that the relation references a missing member.
+func (c *checker) missingMember(relation model.ID, typ model.EntityType, id model.ID) {
+	c.missingMembers++
+	c.list("%c%d in r%d\n", typeChars[typ], id, relation)
+
+	if !c.broken.add(relation) {
+		c.relationsMissing++
+	}
+}
+
+// list writes an ID line if IDs are listed.
+func (c *checker) list(format string, args ...any) {
+	if c.listing != nil {
+		fmt.Fprintf(c.listing, format, args...)
+	}
+}
+
+// typeChars maps entity types to the characters that prefix their IDs.
+var typeChars = map[model.EntityType]byte{
+	model.NODE:     'n',
+	model.WAY:      'w',
+	model.RELATION: 'r',
+}
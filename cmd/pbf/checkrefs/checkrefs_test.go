@@ -0,0 +1,118 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkrefs
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2/model"
+)
+
+func TestRunCheckRefs(t *testing.T) {
+	f, err := os.Open("../../../testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, err := runCheckRefs(f, nil, false)
+	require.NoError(t, err)
+
+	// the sample is an extract, unsorted within each type, whose relations
+	// reference entities outside of it
+	assert.Equal(t, report{
+		nodes:            290,
+		ways:             44,
+		relations:        5,
+		unordered:        167,
+		relationsMissing: 4,
+		missingMembers:   234,
+	}, *r)
+	assert.False(t, r.ok())
+}
+
+func TestCheck(t *testing.T) {
+	var listing bytes.Buffer
+
+	c := &checker{ids: [3]bitset{{}, {}, {}}, broken: bitset{}, listing: &listing}
+
+	for _, e := range []model.Entity{
+		&model.Node{ID: 1},
+		&model.Node{ID: 3},
+		&model.Node{ID: 2},
+		&model.Node{ID: 3},
+		&model.Way{ID: 1, NodeIDs: []model.ID{1, 2, 3}},
+		&model.Way{ID: 2, NodeIDs: []model.ID{1, 4, 5}},
+		&model.Relation{ID: 1, Members: []model.Member{
+			{ID: 2, Type: model.WAY},
+			{ID: 3, Type: model.RELATION},
+		}},
+		&model.Relation{ID: 2, Members: []model.Member{
+			{ID: 1, Type: model.RELATION},
+			{ID: 9, Type: model.NODE},
+		}},
+	} {
+		c.check(e)
+	}
+
+	c.checkPending()
+
+	assert.Equal(t, report{
+		nodes:            4,
+		ways:             2,
+		relations:        2,
+		duplicates:       1,
+		unordered:        1,
+		waysMissingNodes: 1,
+		missingNodes:     2,
+		relationsMissing: 2,
+		missingMembers:   2,
+	}, c.report)
+	assert.Equal(t, "unordered n2\n"+
+		"duplicate n3\n"+
+		"n4 in w2\n"+
+		"n5 in w2\n"+
+		"n9 in r2\n"+
+		"r3 in r1\n", listing.String())
+
+	var out bytes.Buffer
+	c.render(&out)
+
+	assert.Equal(t, "Nodes: 4\n"+
+		"Ways: 2\n"+
+		"Relations: 2\n"+
+		"DuplicateIDs: 1\n"+
+		"OutOfOrderIDs: 1\n"+
+		"WaysWithMissingNodes: 1 (2 missing node references)\n"+
+		"RelationsWithMissingMembers: 2 (2 missing member references)\n", out.String())
+}
+
+func TestBitset(t *testing.T) {
+	s := bitset{}
+
+	for _, id := range []model.ID{0, 1, 63, 64, 1 << chunkBits, -1, -(1 << chunkBits) - 1, 1 << 40} {
+		assert.False(t, s.has(id), "%d", id)
+		assert.False(t, s.add(id), "%d", id)
+		assert.True(t, s.has(id), "%d", id)
+		assert.True(t, s.add(id), "%d", id)
+	}
+
+	assert.False(t, s.has(2))
+	assert.False(t, s.has(-2))
+	assert.Len(t, s, 5)
+}
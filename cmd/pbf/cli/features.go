@@ -0,0 +1,29 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"slices"
+	"strings"
+)
+
+// WithoutSortFeatures returns a copy of the optional features of a header,
+// less those that declare an order of the entities, such as
+// Sort.Type_then_ID, for a copy that might not keep that order.
+func WithoutSortFeatures(features []string) []string {
+	return slices.DeleteFunc(slices.Clone(features), func(feature string) bool {
+		return strings.HasPrefix(feature, "Sort.")
+	})
+}
@@ -0,0 +1,268 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/cli"
+	"m4o.io/pbf/v2/cmd/pbf/internal/sorted"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/osmxml"
+)
+
+// Output formats.
+const (
+	Summary = "summary"
+	OSC     = "osc"
+)
+
+// ErrUnsupportedFormat is returned for an unknown output format.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+var out *os.File
+
+func init() { //nolint:gochecknoinits
+	cli.RootCmd.AddCommand(diffCmd)
+
+	flags := diffCmd.Flags()
+	flags.VarP(cli.NewWriterValue(os.Stdout, &out, "<OSM destination>"), "out", "o", "output file")
+	flags.StringP("format", "f", Summary, "output format, summary or osc")
+	flags.Uint16P("cpu", "c", pbf.DefaultNCpu(), "number of CPUs to use for decoding")
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old OSM source> <new OSM source>",
+	Short: "Show the differences between two OSM files",
+	Long: `Show the entities created, modified and deleted between two OSM files, both
+sorted by type, then ID, as a summary or an OSM change file.  For example:
+
+    pbf diff -f osc old.osm.pbf new.osm.pbf -o changes.osc`,
+	Args: cobra.ExactArgs(2), //nolint:mnd
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+
+		format, err := flags.GetString("format")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ncpu, err := flags.GetUint16("cpu")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		older, err := os.Open(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		newer, err := os.Open(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err = runDiff(older, newer, out, format, pbf.WithNCpus(ncpu)); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = older.Close(); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = newer.Close(); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// sink receives the changes between the inputs.
+type sink interface {
+	change(c model.Change) error
+	close() error
+}
+
+// runDiff writes the changes between older and newer, both sorted by type,
+// then ID, to out in the given format.
+func runDiff(older, newer io.Reader, out io.Writer, format string, opts ...pbf.DecoderOption) error {
+	var (
+		s   sink
+		err error
+	)
+
+	switch format {
+	case Summary:
+		s = &summary{out: out}
+	case OSC:
+		if s, err = newOSC(out); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+
+	ctx := context.Background()
+	opts = append(opts, pbf.WithOrdered())
+
+	od, err := pbf.NewDecoder(ctx, older, opts...)
+	if err != nil {
+		return err
+	}
+
+	defer od.Close()
+
+	nd, err := pbf.NewDecoder(ctx, newer, opts...)
+	if err != nil {
+		return err
+	}
+
+	defer nd.Close()
+
+	if err := diff(od.All(), nd.All(), s.change); err != nil {
+		return errors.Join(err, s.close())
+	}
+
+	return s.close()
+}
+
+// diff joins the entities of older and newer, both sorted by type, then ID,
+// on their type, ID and version.  It emits a change for every entity that is
+// only in older, only in newer, or is in both with another version or, for
+// the same version, other content.
+func diff(older, newer iter.Seq2[model.Entity, error], emit func(c model.Change) error) error {
+	o := sorted.NewCursor("old", older)
+	defer o.Stop()
+
+	n := sorted.NewCursor("new", newer)
+	defer n.Stop()
+
+	if err := o.Advance(); err != nil {
+		return err
+	}
+
+	if err := n.Advance(); err != nil {
+		return err
+	}
+
+	for o.Cur != nil || n.Cur != nil {
+		var (
+			order int  // of the keys, type, ID and version, of the entities
+			same  bool // whether the entities share a type and ID
+		)
+
+		switch {
+		case o.Cur == nil:
+			order = 1
+		case n.Cur == nil:
+			order = -1
+		default:
+			order = model.CompareVersions(o.Cur, n.Cur)
+			same = model.Compare(o.Cur, n.Cur) == 0
+		}
+
+		var err error
+
+		switch {
+		case same:
+			// both inputs have the entity, modified if they have other
+			// versions of it or, for the same version, other content
+			if order != 0 || !model.Equal(o.Cur, n.Cur) {
+				err = emit(model.Change{Action: model.MODIFY, Entity: n.Cur})
+			}
+
+			if err == nil {
+				err = o.Advance()
+			}
+
+			if err == nil {
+				err = n.Advance()
+			}
+		case order < 0:
+			if err = emit(model.Change{Action: model.DELETE, Entity: o.Cur}); err == nil {
+				err = o.Advance()
+			}
+		default:
+			if err = emit(model.Change{Action: model.CREATE, Entity: n.Cur}); err == nil {
+				err = n.Advance()
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// summary counts the changes of each action, by entity type.
+type summary struct {
+	out    io.Writer
+	counts [3][3]int64
+}
+
+func (s *summary) change(c model.Change) error {
+	s.counts[model.TypeOf(c.Entity)][c.Action]++
+
+	return nil
+}
+
+func (s *summary) close() error {
+	for _, t := range []struct {
+		name string
+		typ  model.EntityType
+	}{{"Nodes", model.NODE}, {"Ways", model.WAY}, {"Relations", model.RELATION}} {
+		counts := s.counts[t.typ]
+		if _, err := fmt.Fprintf(s.out, "%s: %s created, %s modified, %s deleted\n", t.name,
+			humanize.Comma(counts[model.CREATE]), humanize.Comma(counts[model.MODIFY]),
+			humanize.Comma(counts[model.DELETE])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// osc writes the changes as an OSM change file.
+type osc struct {
+	e *osmxml.ChangeEncoder
+}
+
+func newOSC(out io.Writer) (*osc, error) {
+	e, err := osmxml.NewChangeEncoder(out, "pbf")
+	if err != nil {
+		return nil, err
+	}
+
+	return &osc{e: e}, nil
+}
+
+func (s *osc) change(c model.Change) error { return s.e.Encode(c) }
+
+func (s *osc) close() error { return s.e.Close() }
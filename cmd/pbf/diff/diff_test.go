@@ -0,0 +1,200 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"bytes"
+	"iter"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2/cmd/pbf/internal/pbftest"
+	"m4o.io/pbf/v2/cmd/pbf/internal/sorted"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/osmxml"
+)
+
+// changed returns a copy of the sample with a node moved, a way deleted and
+// a relation created, along with those changes.
+func changed(t *testing.T) ([]model.Entity, []model.Entity, []model.Change) {
+	t.Helper()
+
+	older := pbftest.SampleEntities(t)
+
+	var (
+		newer   []model.Entity
+		changes []model.Change
+		moved   bool
+		deleted bool
+	)
+
+	for _, e := range older {
+		switch e := e.(type) {
+		case *model.Node:
+			if !moved {
+				n := *e
+				n.Lat += 0.001
+				e, moved = &n, true
+
+				changes = append(changes, model.Change{Action: model.MODIFY, Entity: e})
+			}
+
+			newer = append(newer, e)
+		case *model.Way:
+			if !deleted {
+				deleted = true
+
+				changes = append(changes, model.Change{Action: model.DELETE, Entity: e})
+
+				continue
+			}
+
+			newer = append(newer, e)
+		default:
+			newer = append(newer, e)
+		}
+	}
+
+	created := &model.Relation{
+		ID:      1 << 40,
+		Tags:    map[string]string{"type": "route"},
+		Info:    &model.Info{Version: 1, Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Visible: true},
+		Members: []model.Member{{ID: 1, Type: model.NODE, Role: "stop"}},
+	}
+	newer = append(newer, created)
+	changes = append(changes, model.Change{Action: model.CREATE, Entity: created})
+
+	return older, newer, changes
+}
+
+func TestRunDiffSummary(t *testing.T) {
+	older, newer, _ := changed(t)
+
+	var out bytes.Buffer
+	require.NoError(t, runDiff(pbftest.EncodeSorted(t, older), pbftest.EncodeSorted(t, newer), &out, Summary))
+
+	assert.Equal(t, `Nodes: 0 created, 1 modified, 0 deleted
+Ways: 0 created, 0 modified, 1 deleted
+Relations: 1 created, 0 modified, 0 deleted
+`, out.String())
+}
+
+func TestRunDiffOSC(t *testing.T) {
+	older, newer, want := changed(t)
+
+	var out bytes.Buffer
+	require.NoError(t, runDiff(pbftest.EncodeSorted(t, older), pbftest.EncodeSorted(t, newer), &out, OSC))
+
+	d, err := osmxml.NewChangeDecoder(&out)
+	require.NoError(t, err)
+	assert.Equal(t, "pbf", d.Generator)
+
+	var got []model.Change
+
+	for c, err := range d.All() {
+		require.NoError(t, err)
+
+		got = append(got, c)
+	}
+
+	require.Len(t, got, len(want))
+
+	for i := range want {
+		assert.Equal(t, want[i].Action, got[i].Action)
+		assert.Equal(t, want[i].Entity.GetID(), got[i].Entity.GetID())
+	}
+
+	// deletes are decoded as not visible
+	assert.True(t, want[0].Entity.(*model.Node).Lat.EqualWithin(got[0].Entity.(*model.Node).Lat, model.E7))
+	assert.Equal(t, want[1].Entity.GetInfo().Version, got[1].Entity.GetInfo().Version)
+	assert.True(t, model.Equal(want[2].Entity, got[2].Entity))
+}
+
+func TestRunDiffIdentical(t *testing.T) {
+	entities := pbftest.SampleEntities(t)
+
+	var out bytes.Buffer
+	require.NoError(t, runDiff(pbftest.EncodeSorted(t, entities), pbftest.EncodeSorted(t, entities), &out, OSC))
+
+	d, err := osmxml.NewChangeDecoder(&out)
+	require.NoError(t, err)
+
+	for range d.All() {
+		t.Fatal("unexpected change")
+	}
+}
+
+func TestDiffJoinsOnTypeIDAndVersion(t *testing.T) {
+	info := func(version int32) *model.Info {
+		return &model.Info{Version: version, Visible: true}
+	}
+
+	seq := func(entities ...model.Entity) iter.Seq2[model.Entity, error] {
+		return func(yield func(model.Entity, error) bool) {
+			for _, e := range entities {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	older := seq(
+		&model.Node{ID: 1, Info: info(1)},
+		&model.Node{ID: 2, Info: info(1)},
+		&model.Node{ID: 3, Info: info(4)},
+		&model.Way{ID: 1, Tags: map[string]string{"highway": "path"}, Info: info(2)},
+	)
+	newer := seq(
+		&model.Node{ID: 1, Info: info(2)},
+		&model.Node{ID: 2, Info: info(1)},
+		&model.Node{ID: 3, Info: info(3)},
+		&model.Way{ID: 1, Tags: map[string]string{"highway": "track"}, Info: info(2)},
+	)
+
+	var got []model.Change
+
+	require.NoError(t, diff(older, newer, func(c model.Change) error {
+		got = append(got, c)
+
+		return nil
+	}))
+
+	// another version, an earlier one included, or the same version with
+	// other content, is a modification rather than a deletion and creation
+	assert.Equal(t, []model.Change{
+		{Action: model.MODIFY, Entity: &model.Node{ID: 1, Info: info(2)}},
+		{Action: model.MODIFY, Entity: &model.Node{ID: 3, Info: info(3)}},
+		{Action: model.MODIFY, Entity: &model.Way{ID: 1, Tags: map[string]string{"highway": "track"}, Info: info(2)}},
+	}, got)
+}
+
+func TestRunDiffErrors(t *testing.T) {
+	entities := pbftest.SampleEntities(t)
+
+	err := runDiff(pbftest.EncodeSorted(t, entities), pbftest.EncodeSorted(t, entities), &bytes.Buffer{}, "xml")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	unsorted, err := os.Open(pbftest.SamplePath())
+	require.NoError(t, err)
+	defer unsorted.Close()
+
+	err = runDiff(pbftest.EncodeSorted(t, entities), unsorted, &bytes.Buffer{}, Summary)
+	assert.ErrorIs(t, err, sorted.ErrUnsorted)
+}
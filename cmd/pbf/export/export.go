@@ -0,0 +1,359 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/cli"
+	"m4o.io/pbf/v2/geom"
+	"m4o.io/pbf/v2/locations"
+	"m4o.io/pbf/v2/model"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for an unknown output format.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrUnsupportedLocations is returned for an unknown node location store.
+	ErrUnsupportedLocations = errors.New("unsupported location store")
+	// ErrNotAFile is returned when the input cannot be read twice.
+	ErrNotAFile = errors.New("input is not a regular file")
+)
+
+// defaultAreaTags are the tags that make a closed way an area.
+var defaultAreaTags = []string{
+	"area=yes", "amenity", "building", "landuse", "leisure", "man_made", "military",
+	"natural=water", "natural=wood", "natural=scrub", "natural=wetland", "natural=grassland",
+	"place", "shop", "tourism",
+}
+
+var (
+	in  *os.File
+	out *os.File
+)
+
+func init() { //nolint:gochecknoinits
+	cli.RootCmd.AddCommand(exportCmd)
+
+	flags := exportCmd.Flags()
+	flags.VarP(cli.NewReaderValue(os.Stdin, &in, "<OSM source>"), "in", "i", "input OSM file")
+	flags.VarP(cli.NewWriterValue(os.Stdout, &out, "<GeoJSON destination>"), "out", "o", "output GeoJSON file")
+	flags.StringP("format", "f", GeoJSON, "output format, geojson or geojsonseq")
+	flags.StringSliceP("area-tags", "a", defaultAreaTags, "tags, as key or key=value, that make a closed way an area")
+	flags.BoolP("with-metadata", "m", false, "add version, timestamp, changeset, uid and user properties")
+	flags.StringP("locations", "l", "sparse", "node location store, sparse, dense (Unix only) or sorted")
+	flags.Uint16P("cpu", "c", pbf.DefaultNCpu(), "number of CPUs to use for decoding")
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export OSM entities as GeoJSON",
+	Long: `Export OSM entities as GeoJSON features: tagged nodes as Points, tagged ways
+as LineStrings, or Polygons when they are closed and carry an area tag, and
+multipolygon relations as MultiPolygons.  Tags become the properties of the
+features.  The input is read twice and must be a file, for example:
+
+    pbf export -f geojsonseq -i in.osm.pbf -o out.geojsonseq`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+
+		var (
+			cfg config
+			err error
+		)
+
+		if cfg.format, err = flags.GetString("format"); err != nil {
+			log.Fatal(err)
+		}
+
+		if cfg.areaTags, err = flags.GetStringSlice("area-tags"); err != nil {
+			log.Fatal(err)
+		}
+
+		if cfg.metadata, err = flags.GetBool("with-metadata"); err != nil {
+			log.Fatal(err)
+		}
+
+		if cfg.locations, err = flags.GetString("locations"); err != nil {
+			log.Fatal(err)
+		}
+
+		ncpu, err := flags.GetUint16("cpu")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fi, err := in.Stat()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if !fi.Mode().IsRegular() {
+			log.Fatalf("%v: %s", ErrNotAFile, in.Name())
+		}
+
+		if err = runExport(in, fi.Size(), out, cfg, pbf.WithNCpus(ncpu)); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = in.Close(); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// config holds the options of an export.
+type config struct {
+	format    string   // GeoJSON or GeoJSONSeq
+	areaTags  []string // the tags, as key or key=value, of areas
+	metadata  bool     // add the entity metadata to the properties
+	locations string   // the node location store
+}
+
+// runExport writes the entities of the first size bytes of in to out as
+// GeoJSON features.  The entities are decoded in file order, which must
+// have nodes before ways, and ways before relations.
+func runExport(in io.ReaderAt, size int64, out io.Writer, cfg config, opts ...pbf.DecoderOption) error {
+	ctx := context.Background()
+
+	fw, err := newFeatureWriter(out, cfg.format, cfg.metadata)
+	if err != nil {
+		return err
+	}
+
+	store, err := newStore(cfg.locations)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	members, err := multipolygonWays(ctx, in, size, opts...)
+	if err != nil {
+		return err
+	}
+
+	d, err := pbf.NewDecoderAt(ctx, in, size, opts...)
+	if err != nil {
+		return err
+	}
+
+	x := &exporter{
+		fw:       fw,
+		areaTags: parseAreaTags(cfg.areaTags),
+		store:    store,
+		members:  members,
+		ways:     map[model.ID]*model.Way{},
+	}
+
+	for e, err := range d.All() {
+		if err != nil {
+			return err
+		}
+
+		if err := x.export(e); err != nil {
+			return err
+		}
+	}
+
+	if x.skipped > 0 {
+		slog.Warn("skipped entities whose geometry could not be assembled", "count", x.skipped)
+	}
+
+	return fw.close()
+}
+
+// newStore returns the node location store called name.
+func newStore(name string) (locations.Store, error) {
+	switch name {
+	case "sparse":
+		return locations.NewSparseMap(), nil
+	case "dense":
+		d, err := locations.NewDense("")
+		if errors.Is(err, locations.ErrDenseUnsupported) {
+			return nil, fmt.Errorf("%w: %q: %w", ErrUnsupportedLocations, name, err)
+		} else if err != nil {
+			return nil, err
+		}
+
+		return d, nil
+	case "sorted":
+		return locations.NewSortedArray("")
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedLocations, name)
+	}
+}
+
+// multipolygonWays returns the IDs of the ways that are members of
+// multipolygon relations.
+func multipolygonWays(ctx context.Context, in io.ReaderAt, size int64, opts ...pbf.DecoderOption) (map[model.ID]bool, error) {
+	d, err := pbf.NewDecoderAt(ctx, in, size, append(opts, pbf.WithEntityTypes(model.RELATION))...)
+	if err != nil {
+		return nil, err
+	}
+
+	members := map[model.ID]bool{}
+
+	for r, err := range d.Relations() {
+		if err != nil {
+			return nil, err
+		}
+
+		if !geom.IsMultipolygon(r) {
+			continue
+		}
+
+		for _, m := range r.Members {
+			if m.Type == model.WAY {
+				members[m.ID] = true
+			}
+		}
+	}
+
+	return members, nil
+}
+
+// areaTags maps the keys of area tags to their values, or to nil if any
+// value will do.
+type areaTags map[string][]string
+
+func parseAreaTags(tags []string) areaTags {
+	at := areaTags{}
+
+	for _, tag := range tags {
+		if k, v, ok := strings.Cut(tag, "="); ok {
+			at[k] = append(at[k], v)
+		} else {
+			at[k] = nil
+		}
+	}
+
+	return at
+}
+
+// isArea reports whether the closed way w is an area, rather than a loop.
+func (at areaTags) isArea(w *model.Way) bool {
+	if !geom.IsClosed(w) || w.Tags["area"] == "no" {
+		return false
+	}
+
+	for k, v := range w.Tags {
+		values, ok := at[k]
+		if !ok {
+			continue
+		}
+
+		if values == nil || slices.Contains(values, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exporter turns entities, in file order, into features.
+type exporter struct {
+	fw       *featureWriter
+	areaTags areaTags
+	store    locations.Store
+
+	members map[model.ID]bool       // the ways of multipolygons
+	ways    map[model.ID]*model.Way // the ways of multipolygons, once read
+
+	skipped int // the number of entities whose geometry is broken
+}
+
+func (x *exporter) export(e model.Entity) error {
+	switch e := e.(type) {
+	case *model.Node:
+		if err := x.store.Set(e.ID, e.Lat, e.Lon); err != nil {
+			return err
+		}
+
+		if len(e.Tags) == 0 {
+			return nil
+		}
+
+		return x.fw.write(e, point(e.Lat, e.Lon))
+	case *model.Way:
+		if x.members[e.ID] {
+			x.ways[e.ID] = e
+		}
+
+		if len(e.Tags) == 0 {
+			return nil
+		}
+
+		if x.areaTags.isArea(e) {
+			p, err := geom.WayPolygon(e, x.store)
+			if err != nil {
+				return x.skip(err)
+			}
+
+			return x.fw.write(e, polygon(*p))
+		}
+
+		line, err := geom.WayLineString(e, x.store)
+		if err != nil {
+			return x.skip(err)
+		}
+
+		return x.fw.write(e, lineString(line))
+	case *model.Relation:
+		if !geom.IsMultipolygon(e) {
+			return nil
+		}
+
+		mp, err := geom.Multipolygon(e, x.way, x.store)
+		if err != nil {
+			return x.skip(err)
+		}
+
+		return x.fw.write(e, multiPolygon(mp))
+	}
+
+	return nil
+}
+
+// way returns the multipolygon way id, if it has been read.
+func (x *exporter) way(id model.ID) (*model.Way, bool) {
+	w, ok := x.ways[id]
+
+	return w, ok
+}
+
+// skip counts an entity whose geometry could not be assembled.
+func (x *exporter) skip(err error) error {
+	slog.Debug("skipping entity", "error", err)
+
+	x.skipped++
+
+	return nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/locations"
+	"m4o.io/pbf/v2/model"
+)
+
+func openSample(t *testing.T) (*os.File, int64) {
+	t.Helper()
+
+	f, err := os.Open("../../../testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+
+	return f, fi.Size()
+}
+
+func TestRunExportGeoJSON(t *testing.T) {
+	f, size := openSample(t)
+
+	var buf bytes.Buffer
+
+	cfg := config{format: GeoJSON, areaTags: defaultAreaTags, metadata: true, locations: "sparse"}
+	require.NoError(t, runExport(f, size, &buf, cfg))
+
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			ID       string `json:"id"`
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &fc))
+	assert.Equal(t, "FeatureCollection", fc.Type)
+
+	counts := map[string]int{}
+
+	for _, f := range fc.Features {
+		counts[f.Geometry.Type]++
+
+		assert.Contains(t, f.Properties, "@version", f.ID)
+
+		switch f.Geometry.Type {
+		case "Point":
+			assert.Equal(t, "n", f.ID[:1])
+		case "LineString", "Polygon":
+			assert.Equal(t, "w", f.ID[:1])
+		case "MultiPolygon":
+			assert.Equal(t, "r", f.ID[:1])
+		}
+	}
+
+	nodes, ways := taggedSample(t)
+
+	assert.Equal(t, nodes, counts["Point"])
+	assert.Equal(t, ways, counts["LineString"]+counts["Polygon"])
+	assert.Positive(t, counts["Polygon"])
+}
+
+func TestRunExportGeoJSONSeq(t *testing.T) {
+	f, size := openSample(t)
+
+	var buf bytes.Buffer
+
+	cfg := config{format: GeoJSONSeq, areaTags: defaultAreaTags, locations: "sparse"}
+	require.NoError(t, runExport(f, size, &buf, cfg))
+
+	texts := bytes.Split(buf.Bytes(), []byte{recordSeparator})
+	require.Empty(t, texts[0])
+
+	for _, text := range texts[1:] {
+		var feature map[string]any
+
+		require.NoError(t, json.Unmarshal(text, &feature))
+		assert.Equal(t, "Feature", feature["type"])
+		assert.NotContains(t, feature["properties"], "@version")
+	}
+
+	nodes, ways := taggedSample(t)
+	assert.Equal(t, nodes+ways, len(texts)-1)
+}
+
+func TestPropertiesOmitMissingTimestamp(t *testing.T) {
+	fw, err := newFeatureWriter(&bytes.Buffer{}, GeoJSON, true)
+	require.NoError(t, err)
+
+	props := fw.properties(&model.Node{ID: 1, Info: &model.Info{Version: 1, Visible: true}})
+	assert.Contains(t, props, "@version")
+	assert.NotContains(t, props, "@timestamp")
+
+	ts := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	props = fw.properties(&model.Node{ID: 1, Info: &model.Info{Version: 1, Timestamp: ts, Visible: true}})
+	assert.Equal(t, "2024-05-01T00:00:00Z", props["@timestamp"])
+}
+
+func TestRunExportRejectsUnknownFormat(t *testing.T) {
+	f, size := openSample(t)
+
+	err := runExport(f, size, &bytes.Buffer{}, config{format: "kml", locations: "sparse"})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestRunExportWithDenseLocations(t *testing.T) {
+	f, size := openSample(t)
+
+	var buf bytes.Buffer
+
+	err := runExport(f, size, &buf, config{format: GeoJSON, areaTags: defaultAreaTags, locations: "dense"})
+	if errors.Is(err, locations.ErrDenseUnsupported) {
+		assert.ErrorIs(t, err, ErrUnsupportedLocations)
+
+		return
+	}
+
+	require.NoError(t, err)
+	assert.NotZero(t, buf.Len())
+}
+
+func TestRunExportRejectsUnknownLocations(t *testing.T) {
+	f, size := openSample(t)
+
+	err := runExport(f, size, &bytes.Buffer{}, config{format: GeoJSON, locations: "btree"})
+	assert.ErrorIs(t, err, ErrUnsupportedLocations)
+}
+
+func TestIsArea(t *testing.T) {
+	at := parseAreaTags([]string{"building", "natural=water"})
+	closed := []model.ID{1, 2, 3, 1}
+
+	test_cases := []struct {
+		name    string
+		nodeIDs []model.ID
+		tags    map[string]string
+		want    bool
+	}{
+		{"building", closed, map[string]string{"building": "yes"}, true},
+		{"water", closed, map[string]string{"natural": "water"}, true},
+		{"coastline", closed, map[string]string{"natural": "coastline"}, false},
+		{"area=no", closed, map[string]string{"building": "yes", "area": "no"}, false},
+		{"open", []model.ID{1, 2, 3}, map[string]string{"building": "yes"}, false},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, at.isArea(&model.Way{NodeIDs: tc.nodeIDs, Tags: tc.tags}))
+		})
+	}
+}
+
+// taggedSample returns the number of tagged nodes and ways of the sample.
+func taggedSample(t *testing.T) (nodes, ways int) {
+	t.Helper()
+
+	f, size := openSample(t)
+
+	d, err := pbf.NewDecoderAt(context.Background(), f, size)
+	require.NoError(t, err)
+
+	for e, err := range d.All() {
+		require.NoError(t, err)
+
+		if len(e.GetTags()) == 0 {
+			continue
+		}
+
+		switch e.(type) {
+		case *model.Node:
+			nodes++
+		case *model.Way:
+			ways++
+		}
+	}
+
+	return nodes, ways
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// Output formats.
+const (
+	GeoJSON    = "geojson"
+	GeoJSONSeq = "geojsonseq"
+)
+
+// recordSeparator starts each text of a GeoJSON text sequence (RFC 8142).
+const recordSeparator = 0x1e
+
+// feature is a GeoJSON feature.
+type feature struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id"`
+	Geometry   geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// geometry is a GeoJSON geometry.
+type geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// position is a GeoJSON position: longitude, then latitude.
+type position [2]model.Degrees
+
+// featureWriter writes features as a GeoJSON feature collection or text
+// sequence.
+type featureWriter struct {
+	w        *bufio.Writer
+	format   string
+	metadata bool // add the entity metadata to the properties
+	n        int  // the number of features written
+}
+
+func newFeatureWriter(w io.Writer, format string, metadata bool) (*featureWriter, error) {
+	switch format {
+	case GeoJSON, GeoJSONSeq:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+
+	fw := &featureWriter{w: bufio.NewWriter(w), format: format, metadata: metadata}
+
+	if format == GeoJSON {
+		if _, err := fw.w.WriteString(`{"type":"FeatureCollection","features":[`); err != nil {
+			return nil, err
+		}
+	}
+
+	return fw, nil
+}
+
+// write writes the geometry g of the entity e.
+func (fw *featureWriter) write(e model.Entity, g geometry) error {
+	b, err := json.Marshal(feature{
+		Type:       "Feature",
+		ID:         featureID(e),
+		Geometry:   g,
+		Properties: fw.properties(e),
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fw.format == GeoJSONSeq:
+		err = fw.w.WriteByte(recordSeparator)
+	case fw.n > 0:
+		err = fw.w.WriteByte(',')
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := fw.w.Write(b); err != nil {
+		return err
+	}
+
+	fw.n++
+
+	return fw.w.WriteByte('\n')
+}
+
+// close ends the output and flushes it.
+func (fw *featureWriter) close() error {
+	if fw.format == GeoJSON {
+		if _, err := fw.w.WriteString("]}\n"); err != nil {
+			return err
+		}
+	}
+
+	return fw.w.Flush()
+}
+
+// properties returns the tags of e, and its metadata if wanted, under keys
+// prefixed with @.
+func (fw *featureWriter) properties(e model.Entity) map[string]any {
+	props := make(map[string]any, len(e.GetTags()))
+	for k, v := range e.GetTags() {
+		props[k] = v
+	}
+
+	if info := e.GetInfo(); fw.metadata && info != nil {
+		props["@version"] = info.Version
+		props["@changeset"] = info.Changeset
+		props["@uid"] = info.UID
+		props["@user"] = info.User
+
+		// entities read without metadata have no timestamp
+		if !info.Timestamp.IsZero() {
+			props["@timestamp"] = info.Timestamp.UTC().Format(time.RFC3339)
+		}
+	}
+
+	return props
+}
+
+// featureID returns the ID of the feature of e, such as n123 for node 123.
+func featureID(e model.Entity) string {
+	var prefix string
+
+	switch e.(type) {
+	case *model.Node:
+		prefix = "n"
+	case *model.Way:
+		prefix = "w"
+	default:
+		prefix = "r"
+	}
+
+	return prefix + strconv.FormatInt(int64(e.GetID()), 10)
+}
+
+func point(lat, lon model.Degrees) geometry {
+	return geometry{Type: "Point", Coordinates: position{lon, lat}}
+}
+
+func lineString(line model.LineString) geometry {
+	return geometry{Type: "LineString", Coordinates: positions(line)}
+}
+
+func polygon(p model.Polygon) geometry {
+	return geometry{Type: "Polygon", Coordinates: rings(p)}
+}
+
+func multiPolygon(mp model.MultiPolygon) geometry {
+	coordinates := make([][][]position, len(mp))
+	for i, p := range mp {
+		coordinates[i] = rings(p)
+	}
+
+	return geometry{Type: "MultiPolygon", Coordinates: coordinates}
+}
+
+func positions(points []model.Point) []position {
+	ps := make([]position, len(points))
+	for i, p := range points {
+		ps[i] = position{p.Lon, p.Lat}
+	}
+
+	return ps
+}
+
+// rings returns the outer, then inner, rings of p, which GeoJSON closes by
+// repeating their first position.
+func rings(p model.Polygon) [][]position {
+	rs := make([][]position, 0, 1+len(p.Inner))
+
+	for _, r := range append([]model.Ring{p.Outer}, p.Inner...) {
+		rs = append(rs, positions(append(r[:len(r):len(r)], r[0])))
+	}
+
+	return rs
+}
@@ -17,12 +17,12 @@ package info
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/dustin/go-humanize"
@@ -121,6 +121,15 @@ var infoCmd = &cobra.Command{
 	},
 }
 
+// counter is a pbf.Handler that counts the entities of each type.
+type counter struct {
+	nodes, ways, relations atomic.Int64
+}
+
+func (c *counter) Node(*model.Node)         { c.nodes.Add(1) }
+func (c *counter) Way(*model.Way)           { c.ways.Add(1) }
+func (c *counter) Relation(*model.Relation) { c.relations.Add(1) }
+
 func runInfo(in io.Reader, extended bool, opts ...pbf.DecoderOption) *extendedHeader {
 	ctx := context.Background()
 
@@ -133,39 +142,15 @@ func runInfo(in io.Reader, extended bool, opts ...pbf.DecoderOption) *extendedHe
 
 	info := &extendedHeader{Header: d.Header}
 
-	var nc, wc, rc int64
-
 	if extended {
-	done:
-		for {
-			entities, err := d.Decode()
-			switch {
-			case errors.Is(err, io.EOF):
-				break done
-			case err != nil:
-				panic(err.Error())
-			default:
-				for _, obj := range entities {
-					switch t := obj.(type) {
-					case *model.Node:
-						// Process Node obj.
-						nc++
-					case *model.Way:
-						// Process Way obj.
-						wc++
-					case *model.Relation:
-						// Process Relation obj.
-						rc++
-					default:
-						panic(fmt.Sprintf("unknown type %T\n", t))
-					}
-				}
-			}
+		c := &counter{}
+		if err := d.Apply(c); err != nil {
+			log.Fatal(err)
 		}
 
-		info.NodeCount = nc
-		info.WayCount = wc
-		info.RelationCount = rc
+		info.NodeCount = c.nodes.Load()
+		info.WayCount = c.ways.Load()
+		info.RelationCount = c.relations.Load()
 	}
 
 	return info
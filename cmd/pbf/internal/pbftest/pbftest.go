@@ -0,0 +1,100 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pbftest provides the fixtures shared by the tests of the pbf
+// commands.
+package pbftest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/model"
+)
+
+// Decoder is satisfied by the PBF, XML and o5m decoders.
+type Decoder interface {
+	Decode() ([]model.Entity, error)
+}
+
+// SamplePath returns the path of testdata/sample.osm.pbf.
+func SamplePath() string {
+	_, file, _, _ := runtime.Caller(0)
+
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "..", "testdata", "sample.osm.pbf")
+}
+
+// SampleEntities returns the entities of the sample, sorted by type, then ID.
+func SampleEntities(t *testing.T) []model.Entity {
+	t.Helper()
+
+	f, err := os.Open(SamplePath())
+	require.NoError(t, err)
+	defer f.Close()
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+
+	d, err := pbf.NewDecoderAt(context.Background(), f, fi.Size())
+	require.NoError(t, err)
+	defer d.Close()
+
+	entities := DecodeAll(t, d)
+	slices.SortFunc(entities, model.Compare)
+
+	return entities
+}
+
+// EncodeSorted encodes the entities, sorted by type, then ID, with the
+// encoder options opts.
+func EncodeSorted(t *testing.T, entities []model.Entity, opts ...pbf.EncoderOption) *bytes.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	e, err := pbf.NewEncoder(&buf, append(opts, pbf.WithSorted())...)
+	require.NoError(t, err)
+	require.NoError(t, e.EncodeBatch(entities))
+	require.NoError(t, e.Close())
+
+	return bytes.NewReader(buf.Bytes())
+}
+
+// DecodeAll returns all the entities of d, in the order they are decoded.
+func DecodeAll(t *testing.T, d Decoder) []model.Entity {
+	t.Helper()
+
+	var all []model.Entity
+
+	for {
+		entities, err := d.Decode()
+		if errors.Is(err, io.EOF) {
+			return all
+		}
+
+		require.NoError(t, err)
+
+		all = append(all, entities...)
+	}
+}
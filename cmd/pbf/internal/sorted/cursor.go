@@ -0,0 +1,70 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sorted walks the entities of OSM inputs sorted by type, then ID, as
+// the commands that join such inputs do.
+package sorted
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// ErrUnsorted is returned when an input is not sorted by type, then ID.
+var ErrUnsorted = errors.New("input is not sorted by type, then ID")
+
+// Cursor walks the entities of a sorted input, checking their order.
+type Cursor struct {
+	Cur model.Entity // the current entity, or nil at the end of the input
+
+	name string // names the input in errors
+	next func() (model.Entity, error, bool)
+	stop func()
+}
+
+// NewCursor returns a cursor, before the first of entities, over the input
+// called name.  The cursor must be stopped once it is no longer needed.
+func NewCursor(name string, entities iter.Seq2[model.Entity, error]) *Cursor {
+	next, stop := iter.Pull2(entities)
+
+	return &Cursor{name: name, next: next, stop: stop}
+}
+
+// Advance moves to the next entity, failing with ErrUnsorted if it does not
+// follow the current one.
+func (c *Cursor) Advance() error {
+	e, err, ok := c.next()
+
+	switch {
+	case !ok:
+		c.Cur = nil
+	case err != nil:
+		return err
+	case c.Cur != nil && model.Compare(c.Cur, e) >= 0:
+		return fmt.Errorf("%w: %s: %s %d follows %s %d", ErrUnsorted, c.name,
+			model.TypeOf(e), e.GetID(), model.TypeOf(c.Cur), c.Cur.GetID())
+	default:
+		c.Cur = e
+	}
+
+	return nil
+}
+
+// Stop ends the walk, releasing the input.
+func (c *Cursor) Stop() {
+	c.stop()
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sorted
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2/model"
+)
+
+func entities(es ...model.Entity) func(yield func(model.Entity, error) bool) {
+	return func(yield func(model.Entity, error) bool) {
+		for _, e := range es {
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestCursorWalksSortedInput(t *testing.T) {
+	want := []model.Entity{&model.Node{ID: 2}, &model.Node{ID: 5}, &model.Way{ID: 1}, &model.Relation{ID: 1}}
+
+	c := NewCursor("base", entities(want...))
+	defer c.Stop()
+
+	var got []model.Entity
+
+	for {
+		require.NoError(t, c.Advance())
+
+		if c.Cur == nil {
+			break
+		}
+
+		got = append(got, c.Cur)
+	}
+
+	assert.True(t, slices.Equal(want, got))
+}
+
+func TestCursorFailsOnUnsortedInput(t *testing.T) {
+	test_cases := []struct {
+		name     string
+		entities []model.Entity
+	}{
+		{"descending", []model.Entity{&model.Node{ID: 2}, &model.Node{ID: 1}}},
+		{"duplicate", []model.Entity{&model.Way{ID: 1}, &model.Way{ID: 1}}},
+		{"type", []model.Entity{&model.Way{ID: 1}, &model.Node{ID: 2}}},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewCursor("base", entities(tc.entities...))
+			defer c.Stop()
+
+			require.NoError(t, c.Advance())
+
+			err := c.Advance()
+			require.ErrorIs(t, err, ErrUnsorted)
+			assert.Contains(t, err.Error(), "base: ")
+		})
+	}
+}
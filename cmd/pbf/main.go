@@ -18,8 +18,16 @@ import (
 	"fmt"
 	"os"
 
+	_ "m4o.io/pbf/v2/cmd/pbf/applychanges"
+	_ "m4o.io/pbf/v2/cmd/pbf/cat"
+	_ "m4o.io/pbf/v2/cmd/pbf/checkrefs"
 	"m4o.io/pbf/v2/cmd/pbf/cli"
+	_ "m4o.io/pbf/v2/cmd/pbf/diff"
+	_ "m4o.io/pbf/v2/cmd/pbf/export"
 	_ "m4o.io/pbf/v2/cmd/pbf/info"
+	_ "m4o.io/pbf/v2/cmd/pbf/merge"
+	_ "m4o.io/pbf/v2/cmd/pbf/sort"
+	_ "m4o.io/pbf/v2/cmd/pbf/tagsfilter"
 )
 
 func main() {
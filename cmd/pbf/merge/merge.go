@@ -0,0 +1,254 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/cli"
+	"m4o.io/pbf/v2/cmd/pbf/internal/sorted"
+	"m4o.io/pbf/v2/model"
+)
+
+// batchSize is the number of entities sent to the encoder at a time.
+const batchSize = 8000
+
+var out *os.File
+
+func init() { //nolint:gochecknoinits
+	cli.RootCmd.AddCommand(mergeCmd)
+
+	flags := mergeCmd.Flags()
+	flags.VarP(cli.NewWriterValue(os.Stdout, &out, "<OSM destination>"), "out", "o", "output OSM file")
+	flags.Uint16P("cpu", "c", pbf.DefaultNCpu(), "number of CPUs to use for decoding")
+}
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <OSM source>...",
+	Short: "Merge sorted OSM files",
+	Long: `Merge OSM files, each sorted by type, then ID, into one sorted OSM file.  An
+entity found in several files is written once, taking the highest version, or
+the one from the first file given if the versions are the same.  For example:
+
+    pbf merge a.osm.pbf b.osm.pbf c.osm.pbf -o merged.osm.pbf`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ncpu, err := cmd.Flags().GetUint16("cpu")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		files := make([]*os.File, len(args))
+		ins := make([]io.Reader, len(args))
+
+		for i, name := range args {
+			if files[i], err = os.Open(name); err != nil {
+				log.Fatal(err)
+			}
+
+			ins[i] = files[i]
+		}
+
+		if err = runMerge(ins, out, pbf.WithNCpus(ncpu)); err != nil {
+			log.Fatal(err)
+		}
+
+		for _, f := range files {
+			if err = f.Close(); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if err = out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// runMerge merges the entities of ins, each sorted by type, then ID, and
+// writes them, sorted, to out.  The merged entities are already in order, so
+// the encoder keeps that order rather than sorting them again.  The bounding
+// box of the output is the union of those of the inputs, and the locations of
+// ways are kept if every input has them.
+func runMerge(ins []io.Reader, out io.Writer, opts ...pbf.DecoderOption) error {
+	ctx := context.Background()
+	opts = append(opts, pbf.WithOrdered())
+
+	var (
+		bbox            *model.BoundingBox
+		locationsOnWays int // the number of inputs with the locations of ways
+	)
+
+	all := make([]iter.Seq2[model.Entity, error], len(ins))
+
+	for i, in := range ins {
+		d, err := pbf.NewDecoder(ctx, in, opts...)
+		if err != nil {
+			return err
+		}
+
+		defer d.Close()
+
+		if d.Header.BoundingBox != nil {
+			if bbox == nil {
+				bbox = model.InitialBoundingBox()
+			}
+
+			bbox.ExpandWithBoundingBox(d.Header.BoundingBox)
+		}
+
+		if slices.Contains(d.Header.RequiredFeatures, pbf.LocationsOnWays) {
+			locationsOnWays++
+		}
+
+		all[i] = d.All()
+	}
+
+	encOpts := []pbf.EncoderOption{
+		pbf.WithInputOrder(),
+		pbf.WithOptionalFeatures(pbf.SortTypeThenID),
+		pbf.WithStreaming(bbox),
+		pbf.WithWritingProgram("pbf"),
+	}
+
+	// the output can only declare the locations of ways if every way has them
+	switch locationsOnWays {
+	case 0:
+	case len(ins):
+		encOpts = append(encOpts, pbf.WithLocationsOnWays())
+	default:
+		slog.Warn("dropping the locations of ways, which only some inputs have",
+			"inputs", locationsOnWays, "of", len(ins))
+	}
+
+	e, err := pbf.NewEncoder(out, encOpts...)
+	if err != nil {
+		return err
+	}
+
+	batch := make([]model.Entity, 0, batchSize)
+
+	err = merge(all, func(entity model.Entity) error {
+		batch = append(batch, entity)
+		if len(batch) < batchSize {
+			return nil
+		}
+
+		err := e.EncodeBatch(batch)
+		batch = make([]model.Entity, 0, batchSize)
+
+		return err
+	})
+	if err == nil && len(batch) > 0 {
+		err = e.EncodeBatch(batch)
+	}
+
+	if err != nil {
+		return errors.Join(err, e.Close())
+	}
+
+	return e.Close()
+}
+
+// merge emits the entities of all, each sorted by type, then ID, in order.
+// Of the entities sharing a type and ID, only the one with the highest
+// version, or the first of those, is emitted.
+func merge(all []iter.Seq2[model.Entity, error], emit func(e model.Entity) error) error {
+	h := make(cursors, 0, len(all))
+
+	for i, entities := range all {
+		c := &cursor{Cursor: sorted.NewCursor(fmt.Sprintf("input %d", i+1), entities), index: i}
+		defer c.Stop()
+
+		if err := c.Advance(); err != nil {
+			return err
+		}
+
+		if c.Cur != nil {
+			h = append(h, c)
+		}
+	}
+
+	heap.Init(&h)
+
+	for len(h) > 0 {
+		best := h[0].Cur
+
+		// the cursors are ordered by input among the entities that share a
+		// type and ID, so best is replaced only by a higher version
+		for len(h) > 0 && model.Compare(h[0].Cur, best) == 0 {
+			c := h[0]
+			if model.Version(c.Cur) > model.Version(best) {
+				best = c.Cur
+			}
+
+			if err := c.Advance(); err != nil {
+				return err
+			}
+
+			if c.Cur == nil {
+				heap.Pop(&h)
+			} else {
+				heap.Fix(&h, 0)
+			}
+		}
+
+		if err := emit(best); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cursor walks the entities of one of the merged inputs.
+type cursor struct {
+	*sorted.Cursor
+	index int // the position of the input among those merged
+}
+
+// cursors is a min-heap of cursors, by their current entity, then input.
+type cursors []*cursor
+
+func (h cursors) Len() int { return len(h) }
+
+func (h cursors) Less(i, j int) bool {
+	return cmp.Or(model.Compare(h[i].Cur, h[j].Cur), cmp.Compare(h[i].index, h[j].index)) < 0
+}
+
+func (h cursors) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *cursors) Push(x any) { *h = append(*h, x.(*cursor)) }
+
+func (h *cursors) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return c
+}
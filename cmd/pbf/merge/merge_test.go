@@ -0,0 +1,157 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/internal/pbftest"
+	"m4o.io/pbf/v2/cmd/pbf/internal/sorted"
+	"m4o.io/pbf/v2/model"
+)
+
+func TestRunMerge(t *testing.T) {
+	entities := pbftest.SampleEntities(t)
+	half := 300 // among the ways, which follow the 290 nodes
+
+	// the second input overlaps the first, with a newer version of an entity
+	// and an older version of another
+	newer := *entities[half-1].(*model.Way)
+	newer.Info = &model.Info{Version: newer.Info.Version + 1, Visible: true}
+	newer.Tags = map[string]string{"highway": "footway"}
+
+	older := *entities[half-2].(*model.Way)
+	older.Info = &model.Info{Version: older.Info.Version - 1, Visible: true}
+
+	second := slices.Clone(entities[half-10:])
+	second[8], second[9] = &older, &newer
+
+	a := pbftest.EncodeSorted(t, entities[:half], pbf.WithStreaming(&model.BoundingBox{Top: 2, Left: 0, Bottom: 1, Right: 1}))
+	b := pbftest.EncodeSorted(t, second, pbf.WithStreaming(&model.BoundingBox{Top: 3, Left: -1, Bottom: 0, Right: 0.5}))
+
+	var out bytes.Buffer
+	require.NoError(t, runMerge([]io.Reader{a, b}, &out))
+
+	d, err := pbf.NewDecoder(context.Background(), &out, pbf.WithOrdered())
+	require.NoError(t, err)
+	defer d.Close()
+
+	assert.Equal(t, &model.BoundingBox{Top: 3, Left: -1, Bottom: 0, Right: 1}, d.Header.BoundingBox)
+	assert.Contains(t, d.Header.OptionalFeatures, "Sort.Type_then_ID")
+
+	var merged []model.Entity
+
+	for e, err := range d.All() {
+		require.NoError(t, err)
+
+		merged = append(merged, e)
+	}
+
+	require.Len(t, merged, len(entities))
+	assert.True(t, slices.IsSortedFunc(merged, model.Compare))
+
+	assert.Equal(t, entities[half-2].GetInfo().Version, merged[half-2].GetInfo().Version)
+	assert.Equal(t, newer.Info.Version, merged[half-1].GetInfo().Version)
+	assert.Equal(t, newer.Tags, merged[half-1].GetTags())
+}
+
+func TestRunMergeKeepsLocationsOnWays(t *testing.T) {
+	way := func(id model.ID) *model.Way {
+		return &model.Way{
+			ID: id, Tags: map[string]string{}, Info: &model.Info{Version: 1, Visible: true},
+			NodeIDs: []model.ID{1, 2}, Lats: []model.Degrees{51.5, 51.6}, Lons: []model.Degrees{-0.1, -0.2},
+		}
+	}
+
+	merged := func(t *testing.T, withLocations bool) []model.Entity {
+		t.Helper()
+
+		opts := []pbf.EncoderOption{pbf.WithLocationsOnWays()}
+		if !withLocations {
+			opts = nil
+		}
+
+		a := pbftest.EncodeSorted(t, []model.Entity{way(1)}, pbf.WithLocationsOnWays())
+		b := pbftest.EncodeSorted(t, []model.Entity{way(2)}, opts...)
+
+		var out bytes.Buffer
+		require.NoError(t, runMerge([]io.Reader{a, b}, &out))
+
+		d, err := pbf.NewDecoder(context.Background(), &out, pbf.WithOrdered())
+		require.NoError(t, err)
+		defer d.Close()
+
+		assert.Equal(t, withLocations, slices.Contains(d.Header.RequiredFeatures, pbf.LocationsOnWays))
+
+		return pbftest.DecodeAll(t, d)
+	}
+
+	got := merged(t, true)
+	require.Len(t, got, 2)
+	assert.Equal(t, way(1).Lats, got[0].(*model.Way).Lats)
+	assert.Equal(t, way(2).Lons, got[1].(*model.Way).Lons)
+
+	// the locations of ways are dropped unless every input has them
+	got = merged(t, false)
+	require.Len(t, got, 2)
+	assert.Nil(t, got[0].(*model.Way).Lats)
+}
+
+func TestRunMergeRejectsUnsortedInput(t *testing.T) {
+	f, err := os.Open(pbftest.SamplePath())
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.ErrorIs(t, runMerge([]io.Reader{f}, io.Discard), sorted.ErrUnsorted)
+}
+
+func TestMergePrefersFirstInputOnTies(t *testing.T) {
+	first := &model.Node{ID: 1, Info: &model.Info{Version: 2}, Lat: 1}
+	second := &model.Node{ID: 1, Info: &model.Info{Version: 2}, Lat: 2}
+
+	seq := func(entities ...model.Entity) func(yield func(model.Entity, error) bool) {
+		return func(yield func(model.Entity, error) bool) {
+			for _, e := range entities {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	var got []model.Entity
+
+	err := merge([]iter.Seq2[model.Entity, error]{
+		seq(second, &model.Way{ID: 1}),
+		seq(first, &model.Node{ID: 2}),
+	}, func(e model.Entity) error {
+		got = append(got, e)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []model.Entity{second, &model.Node{ID: 2}, &model.Way{ID: 1}}, got)
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sort
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/cli"
+)
+
+var out *os.File
+
+func init() { //nolint:gochecknoinits
+	cli.RootCmd.AddCommand(sortCmd)
+
+	flags := sortCmd.Flags()
+	flags.VarP(cli.NewWriterValue(os.Stdout, &out, "<OSM destination>"), "out", "o", "output OSM file")
+	flags.StringP("tmp-dir", "t", os.TempDir(), "directory in which sorted runs are spilled")
+	flags.IntP("run-size", "r", pbf.DefaultSortRunSize, "number of entities sorted in memory before being spilled")
+	flags.Uint16P("cpu", "c", pbf.DefaultNCpu(), "number of CPUs to use for decoding")
+}
+
+var sortCmd = &cobra.Command{
+	Use:   "sort [<OSM source>]",
+	Short: "Sort an OSM file by type, then ID",
+	Long: `Sort the entities of an OSM file, read from stdin if no file is given, by
+type, then ID.  Memory use is bounded by spilling sorted runs to temporary
+files, which are merged into the output.  For example:
+
+    pbf sort -t /var/tmp in.osm.pbf -o sorted.osm.pbf`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+
+		var (
+			cfg config
+			err error
+		)
+
+		if cfg.tmpDir, err = flags.GetString("tmp-dir"); err != nil {
+			log.Fatal(err)
+		}
+
+		if cfg.runSize, err = flags.GetInt("run-size"); err != nil {
+			log.Fatal(err)
+		}
+
+		ncpu, err := flags.GetUint16("cpu")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		in := os.Stdin
+		if len(args) > 0 {
+			if in, err = os.Open(args[0]); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if err = runSort(in, out, cfg, pbf.WithNCpus(ncpu)); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = in.Close(); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// config holds where, and in runs of how many entities, entities are sorted.
+type config struct {
+	tmpDir  string
+	runSize int
+}
+
+// runSort copies the entities of in to out, sorted by type, then ID, carrying
+// over the header of in.
+func runSort(in io.Reader, out io.Writer, cfg config, opts ...pbf.DecoderOption) error {
+	d, err := pbf.NewDecoder(context.Background(), in, opts...)
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	// the encoder removes its store once closed, so it is given a directory
+	// of its own
+	store, err := os.MkdirTemp(cfg.tmpDir, "pbf-sort")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(store)
+
+	encOpts := []pbf.EncoderOption{
+		pbf.WithSorted(),
+		pbf.WithSortRunSize(cfg.runSize),
+		pbf.WithStorePath(store),
+		pbf.WithWritingProgram("pbf"),
+		pbf.WithSource(d.Header.Source),
+		pbf.WithOsmosisReplicationTimestamp(d.Header.OsmosisReplicationTimestamp),
+		pbf.WithOsmosisReplicationSequenceNumber(d.Header.OsmosisReplicationSequenceNumber),
+		pbf.WithOsmosisReplicationBaseURL(d.Header.OsmosisReplicationBaseURL),
+		pbf.WithOptionalFeatures(cli.WithoutSortFeatures(d.Header.OptionalFeatures)...),
+	}
+
+	// the entities that arrive out of order are merged with those already
+	// encoded, so the encoder cannot stream
+	if d.Header.BoundingBox != nil {
+		encOpts = append(encOpts, pbf.WithBoundingBox(d.Header.BoundingBox))
+	}
+
+	if slices.Contains(d.Header.RequiredFeatures, pbf.LocationsOnWays) {
+		encOpts = append(encOpts, pbf.WithLocationsOnWays())
+	}
+
+	e, err := pbf.NewEncoder(out, encOpts...)
+	if err != nil {
+		return err
+	}
+
+	for {
+		entities, err := d.Decode()
+		switch {
+		case errors.Is(err, io.EOF):
+			return e.Close()
+		case err != nil:
+			return errors.Join(err, e.Close())
+		}
+
+		if err := e.EncodeBatch(entities); err != nil {
+			return errors.Join(err, e.Close())
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sort
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/internal/pbftest"
+	"m4o.io/pbf/v2/model"
+)
+
+func TestRunSort(t *testing.T) {
+	f, err := os.Open(pbftest.SamplePath())
+	require.NoError(t, err)
+	defer f.Close()
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+
+	sd, err := pbf.NewDecoderAt(context.Background(), f, fi.Size())
+	require.NoError(t, err)
+	defer sd.Close()
+
+	want := pbftest.DecodeAll(t, sd)
+	require.False(t, slices.IsSortedFunc(want, model.Compare))
+	slices.SortFunc(want, model.Compare)
+
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+
+	tmpDir := t.TempDir()
+
+	var out bytes.Buffer
+	require.NoError(t, runSort(f, &out, config{tmpDir: tmpDir, runSize: 50}))
+
+	d, err := pbf.NewDecoder(context.Background(), &out, pbf.WithOrdered())
+	require.NoError(t, err)
+	defer d.Close()
+
+	assert.Equal(t, sd.Header.BoundingBox, d.Header.BoundingBox)
+	assert.Equal(t, []string{"Sort.Type_then_ID"}, d.Header.OptionalFeatures)
+
+	got := pbftest.DecodeAll(t, d)
+	require.Len(t, got, len(want))
+
+	for i := range want {
+		assert.True(t, model.Equal(want[i], got[i]), "%v != %v", want[i], got[i])
+	}
+
+	// the spilled runs are removed, but not the directory they were in
+	left, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, left)
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagsfilter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/cmd/pbf/cli"
+	"m4o.io/pbf/v2/tagfilter"
+)
+
+var (
+	in  *os.File
+	out *os.File
+)
+
+func init() { //nolint:gochecknoinits
+	cli.RootCmd.AddCommand(tagsFilterCmd)
+
+	flags := tagsFilterCmd.Flags()
+	flags.VarP(cli.NewReaderValue(os.Stdin, &in, "<OSM source>"), "in", "i", "input OSM file")
+	flags.VarP(cli.NewWriterValue(os.Stdout, &out, "<OSM destination>"), "out", "o", "output OSM file")
+	flags.Uint32P("unprocessed-batch-size", "u", pbf.DefaultBatchSize, "batch size for unprocessed blobs")
+	flags.Uint16P("cpu", "c", pbf.DefaultNCpu(), "number of CPUs to use for scanning")
+	flags.BoolP("silent", "s", false, "silence progress bar")
+}
+
+var tagsFilterCmd = &cobra.Command{
+	Use:   "tags-filter <expression>",
+	Short: "Filter OSM entities by their tags",
+	Long: `Filter OSM entities by their tags, keeping those that match the expression,
+for example:
+
+    pbf tags-filter -i in.osm.pbf -o out.osm.pbf 'highway=primary,secondary and not access=private'`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+
+		expr, err := tagfilter.Parse(strings.Join(args, " "))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		silent, err := flags.GetBool("silent")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var win io.ReadCloser
+		if silent {
+			win = in
+		} else {
+			win, err = cli.WrapInputFile(in)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		var opts []pbf.DecoderOption
+
+		ncpu, err := flags.GetUint16("cpu")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		opts = append(opts, pbf.WithNCpus(ncpu))
+
+		batchSize, err := flags.GetUint32("unprocessed-batch-size")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		opts = append(opts, pbf.WithProtoBatchSize(int(batchSize)))
+
+		if err = runTagsFilter(win, out, expr, opts...); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = win.Close(); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// runTagsFilter copies the entities of in whose tags match expr to out,
+// carrying over the source and replication details of the input's header, and
+// the locations of ways if the input has them.
+func runTagsFilter(in io.Reader, out io.Writer, expr *tagfilter.Expr, opts ...pbf.DecoderOption) error {
+	ctx := context.Background()
+
+	d, err := pbf.NewDecoder(ctx, in, append(opts, pbf.WithTagFilter(expr))...)
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	encOpts := []pbf.EncoderOption{
+		pbf.WithWritingProgram("pbf"),
+		pbf.WithSource(d.Header.Source),
+		pbf.WithOsmosisReplicationTimestamp(d.Header.OsmosisReplicationTimestamp),
+		pbf.WithOsmosisReplicationSequenceNumber(d.Header.OsmosisReplicationSequenceNumber),
+		pbf.WithOsmosisReplicationBaseURL(d.Header.OsmosisReplicationBaseURL),
+	}
+
+	if slices.Contains(d.Header.RequiredFeatures, pbf.LocationsOnWays) {
+		encOpts = append(encOpts, pbf.WithLocationsOnWays())
+	}
+
+	e, err := pbf.NewEncoder(out, encOpts...)
+	if err != nil {
+		return err
+	}
+
+	for {
+		entities, err := d.Decode()
+		switch {
+		case errors.Is(err, io.EOF):
+			return e.Close()
+		case err != nil:
+			return errors.Join(err, e.Close())
+		}
+
+		if err := e.EncodeBatch(entities); err != nil {
+			return errors.Join(err, e.Close())
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagsfilter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/tagfilter"
+)
+
+func TestRunTagsFilter(t *testing.T) {
+	f, err := os.Open("../../../testdata/sample.osm.pbf")
+	if err != nil {
+		t.Fatalf("Unable to read data file %v", err)
+	}
+
+	defer f.Close()
+
+	expr := tagfilter.MustParse("highway=footway,cycleway")
+
+	var buf bytes.Buffer
+
+	require.NoError(t, runTagsFilter(f, &buf, expr))
+
+	d, err := pbf.NewDecoder(context.Background(), &buf)
+	require.NoError(t, err)
+
+	defer d.Close()
+
+	assert.Equal(t, "pbf", d.Header.WritingProgram)
+
+	var n int
+
+	for {
+		entities, err := d.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		for _, e := range entities {
+			assert.IsType(t, &model.Way{}, e)
+			assert.True(t, expr.Match(e.GetTags()), "%d: %v", e.GetID(), e.GetTags())
+		}
+
+		n += len(entities)
+	}
+
+	assert.Equal(t, 10, n)
+}
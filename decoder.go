@@ -19,6 +19,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"slices"
 	"time"
 
 	"github.com/destel/rill"
@@ -27,6 +29,16 @@ import (
 	"m4o.io/pbf/v2/model"
 )
 
+const (
+	// SortTypeThenID is the optional feature declaring that entities are
+	// ordered by type, then ID.
+	SortTypeThenID = "Sort.Type_then_ID"
+
+	// LocationsOnWays is the required feature declaring that ways hold the
+	// coordinates of their nodes.
+	LocationsOnWays = "LocationsOnWays"
+)
+
 // ErrUnsupportedRequiredFeature is returned when a PBF header contains a required feature
 // that this decoder does not support.
 var ErrUnsupportedRequiredFeature = errors.New("unsupported required feature")
@@ -36,6 +48,9 @@ type Decoder struct {
 	Header   model.Header
 	Entities <-chan rill.Try[[]model.Entity]
 	cancel   context.CancelFunc
+
+	// handlerWorkers is the number of goroutines that invoke handlers.
+	handlerWorkers int
 }
 
 // NewDecoder returns a new decoder, configured with cfg, that reads from
@@ -48,31 +63,138 @@ func NewDecoder(ctx context.Context, rdr io.Reader, opts ...DecoderOption) (*Dec
 		opt(&cfg)
 	}
 
+	d.handlerWorkers = handlerWorkers(&cfg)
 	ctx, d.cancel = context.WithCancel(ctx)
 
-	if hdr, err := decoder.LoadHeader(rdr); err != nil {
+	if hdr, err := loadHeader(rdr); err != nil {
 		return nil, err
 	} else {
-		for _, feature := range hdr.RequiredFeatures {
-			if !isSupportedRequiredFeature(feature) {
-				return nil, fmt.Errorf("%w: %s", ErrUnsupportedRequiredFeature, feature)
-			}
+		d.Header = hdr
+	}
+
+	filter := newFilter(&cfg, d.Header)
+	if cfg.spatial != nil {
+		// the spatial filter needs to see every node and way to decide
+		// which entities are kept
+		filter = decoder.NewFilter(decoder.AllTypes, false, nil)
+	}
+
+	blobs := rill.FromSeq2(decoder.Until(filter, decoder.GenerateBlobReader(ctx, rdr)))
+
+	batches := rill.Batch(blobs, cfg.protoBatchSize, time.Second)
+
+	if cfg.spatial == nil {
+		if cfg.ordered || cfg.handlerMode == SequentialHandlers {
+			d.Entities = rill.OrderedFlatMap(batches, int(cfg.nCPU), decoder.GenerateBatchDecoder(filter))
+		} else {
+			d.Entities = rill.FlatMap(batches, int(cfg.nCPU), decoder.GenerateBatchDecoder(filter))
 		}
 
+		return d, nil
+	}
+
+	// A stream cannot be rewound, so only the single pass strategy is
+	// available; it relies on the entities arriving in file order.
+	if cfg.spatial.strategy != SimpleStrategy {
+		d.cancel()
+
+		return nil, fmt.Errorf("%w: %s requires NewDecoderAt", ErrUnsupportedSpatialStrategy, cfg.spatial.strategy)
+	}
+
+	entities := rill.OrderedFlatMap(batches, int(cfg.nCPU), decoder.GenerateBatchDecoder(filter))
+
+	d.Entities = newSpatialFilter(cfg.spatial).filterSimple(entities, cfg.entityTypes, cfg.tags)
+
+	return d, nil
+}
+
+// NewDecoderAt returns a new decoder, configured with opts, that reads the
+// first size bytes of rdr.  Blob boundaries are scanned ahead of decoding so
+// that several workers can read and inflate disjoint byte ranges of rdr at
+// the same time.  Decoded batches are returned in file order.
+func NewDecoderAt(ctx context.Context, rdr io.ReaderAt, size int64, opts ...DecoderOption) (*Decoder, error) {
+	d := &Decoder{}
+	cfg := defaultDecoderConfig
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	d.handlerWorkers = handlerWorkers(&cfg)
+	ctx, d.cancel = context.WithCancel(ctx)
+
+	if hdr, err := loadHeader(io.NewSectionReader(rdr, 0, size)); err != nil {
+		d.cancel()
+
+		return nil, err
+	} else {
 		d.Header = hdr
 	}
 
-	blobs := rill.FromSeq2(decoder.GenerateBlobReader(ctx, rdr))
+	if cfg.index != nil && cfg.index.Size != size {
+		d.cancel()
 
-	batches := rill.Batch(blobs, cfg.protoBatchSize, time.Second)
+		return nil, fmt.Errorf("%w: index covers %d bytes, input has %d", ErrInvalidIndex, cfg.index.Size, size)
+	}
+
+	filter := newFilter(&cfg, d.Header)
 
-	entities := rill.FlatMap(batches, int(cfg.nCPU), decoder.DecodeBatch)
+	if cfg.spatial == nil {
+		d.Entities = decodeAt(ctx, rdr, size, &cfg, filter)
 
-	d.Entities = entities
+		return d, nil
+	}
+
+	sf := newSpatialFilter(cfg.spatial)
+
+	if cfg.spatial.strategy == SimpleStrategy {
+		all := decoder.NewFilter(decoder.AllTypes, false, nil)
+		d.Entities = sf.filterSimple(decodeAt(ctx, rdr, size, &cfg, all), cfg.entityTypes, cfg.tags)
+
+		return d, nil
+	}
+
+	if err := sf.selectAt(ctx, rdr, size, &cfg, d.Header); err != nil {
+		d.cancel()
+
+		return nil, err
+	}
+
+	d.Entities = sf.filterSelected(decodeAt(ctx, rdr, size, &cfg, filter), int(cfg.nCPU))
 
 	return d, nil
 }
 
+// decodeAt decodes, in file order, the entities selected by f from the first
+// size bytes of rdr.
+func decodeAt(
+	ctx context.Context,
+	rdr io.ReaderAt,
+	size int64,
+	cfg *decoderOptions,
+	f *decoder.Filter,
+) <-chan rill.Try[[]model.Entity] {
+	scanned := decoder.ScanBlobs(ctx, rdr, size)
+	if cfg.index != nil {
+		scanned = indexedLocations(cfg.index, f.Types())
+	}
+
+	locations := rill.FromSeq2(decoder.Until(f, scanned))
+
+	primitives := rill.Filter(locations, 1, func(loc decoder.BlobLocation) (bool, error) {
+		return loc.Type == decoder.DataType, nil
+	})
+
+	return rill.OrderedMap(primitives, int(cfg.nCPU), func(loc decoder.BlobLocation) ([]model.Entity, error) {
+		blob, err := decoder.ReadBlobAt(rdr, loc.Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.DecodeBlob(blob, f)
+	})
+}
+
 // Decode reads the next OSM object and returns either a pointer to Node, Way
 // or Relation struct representing the underlying OpenStreetMap PBF data, or
 // error encountered. The end of the input stream is reported by an io.EOF
@@ -86,15 +208,136 @@ func (d *Decoder) Decode() ([]model.Entity, error) {
 	return decoded.Value, decoded.Error
 }
 
+// All returns an iterator over the decoded entities.  Iteration ends at the
+// end of the input or once an error has been yielded.  The decoder is closed
+// when iteration ends, including when the loop is broken out of early, which
+// stops the background decoding pipeline.
+func (d *Decoder) All() iter.Seq2[model.Entity, error] {
+	return func(yield func(model.Entity, error) bool) {
+		defer d.Close()
+
+		for {
+			entities, err := d.Decode()
+			if errors.Is(err, io.EOF) {
+				return
+			} else if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			for _, e := range entities {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Nodes returns an iterator over the decoded nodes, as for All.  Entities of
+// other types are still decoded unless excluded with WithEntityTypes.
+func (d *Decoder) Nodes() iter.Seq2[*model.Node, error] {
+	return ofType[*model.Node](d.All())
+}
+
+// Ways returns an iterator over the decoded ways, as for All.  Entities of
+// other types are still decoded unless excluded with WithEntityTypes.
+func (d *Decoder) Ways() iter.Seq2[*model.Way, error] {
+	return ofType[*model.Way](d.All())
+}
+
+// Relations returns an iterator over the decoded relations, as for All.
+// Entities of other types are still decoded unless excluded with
+// WithEntityTypes.
+func (d *Decoder) Relations() iter.Seq2[*model.Relation, error] {
+	return ofType[*model.Relation](d.All())
+}
+
+// ofType narrows all to the entities of type E.
+func ofType[E model.Entity](all iter.Seq2[model.Entity, error]) iter.Seq2[E, error] {
+	return func(yield func(E, error) bool) {
+		for e, err := range all {
+			if err != nil {
+				var zero E
+
+				yield(zero, err)
+
+				return
+			}
+
+			if t, ok := e.(E); ok && !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
 // Close will cancel the background decoding pipeline.
 func (d *Decoder) Close() {
 	d.cancel()
 	rill.DrainNB(d.Entities)
 }
 
+// loadHeader reads the OSM header from rdr and verifies that all of its
+// required features are supported.
+func loadHeader(rdr io.Reader) (model.Header, error) {
+	hdr, err := decoder.LoadHeader(rdr)
+	if err != nil {
+		return model.Header{}, err
+	}
+
+	for _, feature := range hdr.RequiredFeatures {
+		if !isSupportedRequiredFeature(feature) {
+			return model.Header{}, fmt.Errorf("%w: %s", ErrUnsupportedRequiredFeature, feature)
+		}
+	}
+
+	return hdr, nil
+}
+
+// newFilter creates the filter selecting the entities to decode from an input
+// with the header hdr.
+func newFilter(cfg *decoderOptions, hdr model.Header) *decoder.Filter {
+	return decoder.NewFilter(cfg.entityTypes, isSorted(hdr), cfg.tags)
+}
+
+// handlerWorkers returns the number of goroutines that invoke the handlers
+// passed to Decoder.Apply.
+func handlerWorkers(cfg *decoderOptions) int {
+	if cfg.handlerMode == SequentialHandlers {
+		return 1
+	}
+
+	return int(cfg.nCPU)
+}
+
+// isSorted reports whether the header declares its entities to be ordered by
+// type, then ID.
+func isSorted(hdr model.Header) bool {
+	return slices.Contains(hdr.OptionalFeatures, SortTypeThenID)
+}
+
+// indexedLocations creates an iterator over the locations of the indexed
+// blobs that hold entities of the given types.
+func indexedLocations(idx *Index, types decoder.TypeSet) func(yield func(decoder.BlobLocation, error) bool) {
+	return func(yield func(decoder.BlobLocation, error) bool) {
+		for _, b := range idx.Blobs {
+			if !slices.ContainsFunc(b.Types, types.Has) {
+				continue
+			}
+
+			loc := decoder.BlobLocation{Type: decoder.DataType, Offset: b.Offset, Size: b.Size}
+			if !yield(loc, nil) {
+				return
+			}
+		}
+	}
+}
+
 func isSupportedRequiredFeature(feature string) bool {
 	switch feature {
-	case "OsmSchema-V0.6", "DenseNodes", "HistoricalInformation":
+	case "OsmSchema-V0.6", "DenseNodes", "HistoricalInformation", LocationsOnWays:
 		return true
 	default:
 		return false
@@ -16,6 +16,10 @@ package pbf
 
 import (
 	"runtime"
+
+	"m4o.io/pbf/v2/internal/decoder"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/tagfilter"
 )
 
 const (
@@ -38,6 +42,15 @@ type decoderOptions struct {
 	protoBufferSize int    // buffer size for protobuf un-marshaling
 	protoBatchSize  int    // batch size for protobuf un-marshaling
 	nCPU            uint16 // the number of CPUs to use for background processing
+
+	entityTypes decoder.TypeSet // the entity types to decode
+	index       *Index          // the index of the input, if known
+
+	spatial *spatialOptions // the spatial filter, if any
+	tags    *tagfilter.Expr // the tag filter, if any
+
+	ordered     bool        // whether NewDecoder keeps batches in file order
+	handlerMode HandlerMode // how Decoder.Apply invokes handlers
 }
 
 // DecoderOption configures how we set up the decoder.
@@ -64,9 +77,68 @@ func WithNCpus(n uint16) DecoderOption {
 	}
 }
 
+// WithEntityTypes restricts decoding to entities of the given types.  Other
+// primitive groups are dropped before their entities are built, and blobs
+// that are known to hold none of the types are skipped without being
+// unpacked.  By default, all entity types are decoded.
+func WithEntityTypes(types ...model.EntityType) DecoderOption {
+	return func(o *decoderOptions) {
+		o.entityTypes = decoder.NewTypeSet(types...)
+	}
+}
+
+// WithIndex provides NewDecoderAt with a previously built index of its
+// input, letting it skip blobs that hold no wanted entity types.  It is
+// ignored by NewDecoder.
+func WithIndex(idx *Index) DecoderOption {
+	return func(o *decoderOptions) {
+		o.index = idx
+	}
+}
+
+// WithSpatialFilter restricts decoding to the nodes within area, along with
+// the ways and relations that reference them, as chosen by strategy.  Only
+// SimpleStrategy is available to NewDecoder; the other strategies make
+// several passes over the input and require NewDecoderAt.
+func WithSpatialFilter(area Area, strategy SpatialStrategy) DecoderOption {
+	return func(o *decoderOptions) {
+		o.spatial = &spatialOptions{area: area, strategy: strategy}
+	}
+}
+
+// WithTagFilter restricts decoding to the entities whose tags match expr.
+// Tags are matched against the string table of each block, so no tag map is
+// built for the entities that are filtered out.  When combined with a spatial
+// filter, the spatial filter sees every entity and expr is applied to the
+// entities it keeps.
+func WithTagFilter(expr *tagfilter.Expr) DecoderOption {
+	return func(o *decoderOptions) {
+		o.tags = expr
+	}
+}
+
+// WithOrdered makes NewDecoder return its batches in file order, as
+// NewDecoderAt always does.  By default, NewDecoder returns batches as soon as
+// they are decoded, in no particular order.
+func WithOrdered() DecoderOption {
+	return func(o *decoderOptions) {
+		o.ordered = true
+	}
+}
+
+// WithHandlerMode selects how Decoder.Apply invokes handlers.  By default,
+// handlers are invoked concurrently.  SequentialHandlers implies WithOrdered,
+// so that handlers see the entities in file order.
+func WithHandlerMode(mode HandlerMode) DecoderOption {
+	return func(o *decoderOptions) {
+		o.handlerMode = mode
+	}
+}
+
 // defaultDecoderConfig provides a default configuration for decoders.
 var defaultDecoderConfig = decoderOptions{
 	protoBufferSize: DefaultBufferSize,
 	protoBatchSize:  DefaultBatchSize,
 	nCPU:            DefaultNCpu(),
+	entityTypes:     decoder.AllTypes,
 }
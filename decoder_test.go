@@ -21,11 +21,15 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/tagfilter"
 )
 
 func TestDecodeSample(t *testing.T) {
@@ -39,7 +43,9 @@ func TestNewDecoderFailsOnUnknownRequiredFeature(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create encoder: %v", err)
 	}
-	enc.Close()
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
 
 	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
 	if err == nil {
@@ -51,6 +57,343 @@ func TestNewDecoderFailsOnUnknownRequiredFeature(t *testing.T) {
 	}
 }
 
+func TestNewDecoderAtPreservesFileOrder(t *testing.T) {
+	in, err := os.Open("testdata/sample.osm.pbf")
+	if err != nil {
+		t.Fatalf("open sample: %v", err)
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		t.Fatalf("stat sample: %v", err)
+	}
+
+	sequential, err := NewDecoder(context.Background(), in, WithNCpus(1))
+	if err != nil {
+		t.Fatalf("create sequential decoder: %v", err)
+	}
+	defer sequential.Close()
+
+	parallel, err := NewDecoderAt(context.Background(), in, fi.Size(), WithNCpus(4))
+	if err != nil {
+		t.Fatalf("create parallel decoder: %v", err)
+	}
+	defer parallel.Close()
+
+	assert.Equal(t, sequential.Header, parallel.Header)
+	assert.Equal(t, decodeIDs(t, sequential), decodeIDs(t, parallel))
+}
+
+func TestWithOrderedKeepsFileOrder(t *testing.T) {
+	in, err := os.Open("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer in.Close()
+
+	fi, err := in.Stat()
+	require.NoError(t, err)
+
+	want, err := NewDecoderAt(context.Background(), in, fi.Size())
+	require.NoError(t, err)
+	defer want.Close()
+
+	d := openSample(t, WithOrdered(), WithNCpus(4), WithProtoBatchSize(1))
+
+	assert.Equal(t, decodeIDs(t, want), decodeIDs(t, d))
+}
+
+func TestNewDecoderAtFailsOnTruncatedInput(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.osm.pbf")
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+
+	truncated := bytes.NewReader(data[:len(data)-1])
+
+	dec, err := NewDecoderAt(context.Background(), truncated, truncated.Size())
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+	defer dec.Close()
+
+	for {
+		_, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			t.Fatal("expected an error decoding a truncated file")
+		}
+		if err != nil {
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+			break
+		}
+	}
+}
+
+func TestNewDecoderAtReportsCancellation(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rdr := bytes.NewReader(data)
+
+	dec, err := NewDecoderAt(ctx, rdr, rdr.Size())
+	require.NoError(t, err)
+	defer dec.Close()
+
+	for {
+		_, err := dec.Decode()
+		require.NotErrorIs(t, err, io.EOF, "expected cancellation to be reported")
+
+		if err != nil {
+			assert.ErrorIs(t, err, context.Canceled)
+
+			break
+		}
+	}
+}
+
+func TestWithEntityTypesKeepsOnlyWantedTypes(t *testing.T) {
+	in, err := os.Open("testdata/sample.osm.pbf")
+	if err != nil {
+		t.Fatalf("open sample: %v", err)
+	}
+	defer in.Close()
+
+	dec, err := NewDecoder(context.Background(), in, WithEntityTypes(model.WAY, model.RELATION))
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+	defer dec.Close()
+
+	var ways, relations int
+
+	for {
+		entities, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		for _, e := range entities {
+			switch e.(type) {
+			case *model.Way:
+				ways++
+			case *model.Relation:
+				relations++
+			default:
+				t.Fatalf("unexpected entity %T", e)
+			}
+		}
+	}
+
+	assert.Equal(t, 44, ways)
+	assert.Equal(t, 5, relations)
+}
+
+func TestWithIndexSkipsUnwantedBlobs(t *testing.T) {
+	in, err := os.Open("testdata/sample.osm.pbf")
+	if err != nil {
+		t.Fatalf("open sample: %v", err)
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		t.Fatalf("stat sample: %v", err)
+	}
+
+	idx, err := BuildIndex(context.Background(), in, fi.Size())
+	if err != nil {
+		t.Fatalf("build index: %v", err)
+	}
+
+	rdr := &recordingReaderAt{r: in}
+
+	dec, err := NewDecoderAt(context.Background(), rdr, fi.Size(), WithIndex(idx), WithEntityTypes(model.WAY))
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+	defer dec.Close()
+
+	ids := decodeIDs(t, dec)
+	assert.Len(t, ids, 44)
+
+	for _, b := range idx.Blobs {
+		if b.HasType(model.WAY) {
+			continue
+		}
+
+		for _, off := range rdr.offsets {
+			if b.Offset <= off && off < b.Offset+b.Size {
+				t.Fatalf("read offset %d within skipped blob %+v", off, b)
+			}
+		}
+	}
+}
+
+func TestWithIndexRejectsMismatchedInput(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.osm.pbf")
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+
+	idx := &Index{Size: int64(len(data)) + 1}
+
+	_, err = NewDecoderAt(context.Background(), bytes.NewReader(data), int64(len(data)), WithIndex(idx))
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatalf("expected ErrInvalidIndex, got: %v", err)
+	}
+}
+
+func TestWithTagFilterKeepsMatchingEntities(t *testing.T) {
+	expr := tagfilter.MustParse("highway=residential,bus_stop or source=naptan_import and not type=site")
+
+	allNodes, allWays, allRelations := decodeSampleAt(t)
+	nodes, ways, relations := decodeSampleAt(t, WithTagFilter(expr))
+
+	assert.Equal(t, keys(matching(allNodes, expr)), keys(nodes))
+	assert.Equal(t, keys(matching(allWays, expr)), keys(ways))
+	assert.Equal(t, keys(matching(allRelations, expr)), keys(relations))
+
+	assert.NotEmpty(t, nodes)
+	assert.NotEmpty(t, ways)
+}
+
+func TestWithTagFilterOnStream(t *testing.T) {
+	expr := tagfilter.MustParse("highway=footway,cycleway")
+
+	in, err := os.Open("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer in.Close()
+
+	dec, err := NewDecoder(context.Background(), in, WithTagFilter(expr))
+	require.NoError(t, err)
+	defer dec.Close()
+
+	nodes, ways, relations := decodeByType(t, dec)
+
+	assert.Empty(t, nodes)
+	assert.Len(t, ways, 10)
+	assert.Empty(t, relations)
+
+	for _, w := range ways {
+		assert.True(t, expr.Match(w.Tags), "way %d: %v", w.ID, w.Tags)
+	}
+}
+
+func TestAllYieldsEveryEntity(t *testing.T) {
+	dec := openSample(t)
+
+	var n int
+
+	for e, err := range dec.All() {
+		require.NoError(t, err)
+		require.NotNil(t, e)
+
+		n++
+	}
+
+	assert.Equal(t, 339, n)
+}
+
+func TestTypedIterators(t *testing.T) {
+	var ways, relations int
+
+	for w, err := range openSample(t).Ways() {
+		require.NoError(t, err)
+		assert.NotEmpty(t, w.NodeIDs)
+
+		ways++
+	}
+
+	for r, err := range openSample(t, WithEntityTypes(model.RELATION)).Relations() {
+		require.NoError(t, err)
+		assert.NotEmpty(t, r.Members)
+
+		relations++
+	}
+
+	assert.Equal(t, 44, ways)
+	assert.Equal(t, 5, relations)
+}
+
+func TestBreakingOutOfAllStopsPipeline(t *testing.T) {
+	dec := openSample(t, WithNCpus(1), WithProtoBatchSize(1))
+
+	for _, err := range dec.Nodes() {
+		require.NoError(t, err)
+
+		break
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for range dec.Entities {
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("decoding pipeline still running after break")
+	}
+}
+
+func openSample(t *testing.T, opts ...DecoderOption) *Decoder {
+	t.Helper()
+
+	in, err := os.Open("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	t.Cleanup(func() { in.Close() })
+
+	dec, err := NewDecoder(context.Background(), in, opts...)
+	require.NoError(t, err)
+
+	return dec
+}
+
+// recordingReaderAt records the offsets of every read.
+type recordingReaderAt struct {
+	r       io.ReaderAt
+	mu      sync.Mutex
+	offsets []int64
+}
+
+func (r *recordingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	r.offsets = append(r.offsets, off)
+	r.mu.Unlock()
+
+	return r.r.ReadAt(p, off)
+}
+
+func decodeIDs(t *testing.T, dec *Decoder) []model.ID {
+	t.Helper()
+
+	var ids []model.ID
+
+	for {
+		entities, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return ids
+		}
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		for _, e := range entities {
+			ids = append(ids, e.GetID())
+		}
+	}
+}
+
 func publicDecodeOsmPbf(t *testing.T, file string, expectedEntries int) {
 	in, err := os.Open(file)
 	if err != nil {
@@ -87,3 +430,15 @@ func publicDecodeOsmPbf(t *testing.T, file string, expectedEntries int) {
 
 	assert.Equal(t, expectedEntries, nEntries, "Incorrect number of entities")
 }
+
+func matching[E model.Entity](entities map[model.ID]E, expr *tagfilter.Expr) map[model.ID]E {
+	matched := map[model.ID]E{}
+
+	for id, e := range entities {
+		if expr.Match(e.GetTags()) {
+			matched[id] = e
+		}
+	}
+
+	return matched
+}
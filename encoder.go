@@ -15,11 +15,17 @@
 package pbf
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"log/slog"
+	"math"
 	"os"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/destel/rill"
 
@@ -27,10 +33,20 @@ import (
 	"m4o.io/pbf/v2/model"
 )
 
-const (
-	numConsumers = 2
-
-	singleCPU = 5
+const numConsumers = 2
+
+var (
+	// ErrWriteHeader is returned when the encoder cannot write the PBF header.
+	ErrWriteHeader = errors.New("write header")
+	// ErrWriteBlock is returned when the encoder cannot write an encoded block.
+	ErrWriteBlock = errors.New("write block")
+	// ErrInvalidCompressionLevel is returned when the compression level is
+	// not valid for the compression algorithm.
+	ErrInvalidCompressionLevel = errors.New("invalid compression level")
+	// ErrInvalidGranularity is returned when the coordinate or date
+	// granularity is not positive, or the date granularity is too fine for
+	// current timestamps.
+	ErrInvalidGranularity = errors.New("invalid granularity")
 )
 
 // Encoder wr and decodes OpenStreetMap PBF data to an input stream.
@@ -41,24 +57,56 @@ type Encoder struct {
 	cfg  *encoderOptions
 	wrtr io.Writer
 
-	err   error
-	close sync.Once
+	mu     sync.Mutex
+	err    error         // the first pipeline error
+	failed chan struct{} // closed once err is set
+	close  sync.Once
 
 	completed sync.WaitGroup
 	closed    sync.WaitGroup
+
+	sorter     *sorter            // the entities that arrived out of order, if any
+	sorterBBox *model.BoundingBox // bounds the nodes held by sorter
 }
 
 // NewEncoder returns a new encoder, configured with options, that reads from
 // reader.  The decoder is initialized with the OSM header.
 func NewEncoder(wrtr io.Writer, opts ...EncoderOption) (*Encoder, error) {
+	return NewEncoderContext(context.Background(), wrtr, opts...)
+}
+
+// NewEncoderContext is NewEncoder with a context.  Once ctx is done, the
+// background encoding pipeline is stopped, the temporary store is removed
+// and Encode, EncodeBatch and Close return ctx.Err().
+func NewEncoderContext(ctx context.Context, wrtr io.Writer, opts ...EncoderOption) (*Encoder, error) {
 	cfg := defaultEncoderConfig
 
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
-	if err := initializeTempStore(&cfg); err != nil {
-		return nil, err
+	if cfg.precision.Granularity <= 0 || cfg.precision.DateGranularity <= 0 {
+		return nil, fmt.Errorf("%w: granularity %d, date granularity %d",
+			ErrInvalidGranularity, cfg.precision.Granularity, cfg.precision.DateGranularity)
+	}
+
+	// the timestamps of ways and relations are int32s, which cannot hold
+	// current timestamps in units of less than about 800 ms
+	if time.Now().UnixMilli()/int64(cfg.precision.DateGranularity) > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: date granularity %d cannot hold current timestamps",
+			ErrInvalidGranularity, cfg.precision.DateGranularity)
+	}
+
+	if err := encoder.CheckCompression(cfg.compression, cfg.compressionLevel); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCompressionLevel, err)
+	}
+
+	if cfg.locationsOnWays && !slices.Contains(cfg.requiredFeatures, LocationsOnWays) {
+		cfg.requiredFeatures = append(cfg.requiredFeatures, LocationsOnWays)
+	}
+
+	if cfg.sorted && !slices.Contains(cfg.optionalFeatures, SortTypeThenID) {
+		cfg.optionalFeatures = append(cfg.optionalFeatures, SortTypeThenID)
 	}
 
 	e := &Encoder{
@@ -73,19 +121,50 @@ func NewEncoder(wrtr io.Writer, opts ...EncoderOption) (*Encoder, error) {
 			OsmosisReplicationBaseURL:        cfg.osmosisReplicationBaseURL,
 		},
 
-		cfg:  &cfg,
-		wrtr: wrtr,
+		cfg:    &cfg,
+		wrtr:   wrtr,
+		failed: make(chan struct{}),
+	}
+
+	// blocks are written straight to wrtr when streaming, otherwise to the
+	// temporary store
+	var blocks io.Writer = wrtr
+
+	if cfg.bbox != nil || cfg.streaming {
+		e.Header.BoundingBox = cfg.bbox
+	}
+
+	if cfg.streaming {
+
+		if err := encoder.SaveHeader(wrtr, e.Header, cfg.compression, cfg.compressionLevel); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrWriteHeader, err)
+		}
+	} else {
+		if err := initializeTempStore(&cfg); err != nil {
+			return nil, err
+		}
+
+		blocks = cfg.wrtr
 	}
 
 	entities := make(chan []model.Entity)
+	forwarded := make(chan []model.Entity)
 
 	e.Entities = entities
 
-	coalesced := encoder.Coalesce(entities, encoder.EntityLimit)
+	go e.forward(ctx, entities, forwarded)
+
+	// sorted entities, and those kept in input order, are batched in order,
+	// otherwise by type as they arrive
+	var coalesced <-chan rill.Try[[]model.Entity]
+	if cfg.sorted || cfg.inputOrder {
+		coalesced = encoder.CoalesceSorted(forwarded, encoder.EntityLimit)
+	} else {
+		coalesced = encoder.Coalesce(forwarded, encoder.EntityLimit)
+	}
+
 	inspected, bboxes := encoder.ExtractBoundingBoxes(coalesced)
-	encoded := rill.OrderedMap(inspected, singleCPU, encoder.EncodeBatch)
-	packed := rill.OrderedMap(encoded, singleCPU, encoder.GenerateBatchPacker(cfg.compression))
-	statuses := encoder.SavePacked(cfg.wrtr, packed)
+	statuses := encoder.SavePacked(blocks, e.pack(inspected))
 
 	// writeHeaderAndBody() will wait for these two consumers to complete
 	e.completed.Add(numConsumers)
@@ -94,35 +173,223 @@ func NewEncoder(wrtr io.Writer, opts ...EncoderOption) (*Encoder, error) {
 
 	// Close() will wait for the header and body to be written
 	e.closed.Add(1)
-	go e.writeHeaderAndBody()
+
+	if cfg.streaming {
+		go e.awaitBody()
+	} else {
+		go e.writeHeaderAndBody(ctx)
+	}
 
 	return e, nil
 }
 
+// pack encodes and compresses batches of entities into blocks, in order.
+func (e *Encoder) pack(batches <-chan rill.Try[[]model.Entity]) <-chan rill.Try[[]byte] {
+	encoded := rill.OrderedMap(batches, int(e.cfg.nCPU), encoder.GenerateBatchEncoder(e.cfg.precision, e.cfg.locationsOnWays))
+
+	return rill.OrderedMap(encoded, int(e.cfg.nCPU), encoder.GenerateBatchPacker(e.cfg.compression, e.cfg.compressionLevel))
+}
+
 // Encode writes an entity into a PBF Blob.
 func (e *Encoder) Encode(entity model.Entity) error {
 	return e.EncodeBatch([]model.Entity{entity})
 }
 
-// EncodeBatch writes an array of entities into a PBF Blob.
+// EncodeBatch writes an array of entities into a PBF Blob.  Once the
+// background encoding pipeline has failed, the entities are dropped and the
+// first pipeline error is returned.
 func (e *Encoder) EncodeBatch(entities []model.Entity) error {
-	e.Entities <- entities
+	// checked first, so that a failure is reported even when the pipeline is
+	// also ready to receive
+	select {
+	case <-e.failed:
+		return e.firstErr()
+	default:
+	}
 
-	return nil
+	select {
+	case e.Entities <- entities:
+		return nil
+	case <-e.failed:
+		return e.firstErr()
+	}
 }
 
-// Close will cancel the background encoding pipeline.
-func (e *Encoder) Close() {
-	e.doClose(io.EOF)
+// Close flushes the background encoding pipeline and waits for the header
+// and body to be written.  It returns the first pipeline error, if any, which
+// wraps either ErrWriteHeader or ErrWriteBlock, or is the error of a done
+// context.
+func (e *Encoder) Close() error {
+	e.close.Do(func() {
+		close(e.Entities)
+	})
 	e.closed.Wait()
+
+	return e.firstErr()
 }
 
-// Close will cancel the background encoding pipeline.
-func (e *Encoder) doClose(err error) {
-	e.close.Do(func() {
+// firstErr returns the first error encountered by the background encoding
+// pipeline, or nil.
+func (e *Encoder) firstErr() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.err
+}
+
+// fail records err unless an earlier error has already been recorded.
+func (e *Encoder) fail(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.err == nil {
 		e.err = err
-		close(e.Entities)
-	})
+		close(e.failed)
+	}
+}
+
+// forward passes the entities sent to the encoder on to the pipeline, until
+// in is closed or ctx is done.  A sorted encoder passes on the entities that
+// arrive in order and holds back the others, see passOrdered.
+func (e *Encoder) forward(ctx context.Context, in <-chan []model.Entity, out chan<- []model.Entity) {
+	defer close(out)
+
+	var last model.Entity // the last entity passed on by a sorted encoder
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.abort(ctx.Err(), in)
+
+			return
+		case entities, ok := <-in:
+			if !ok {
+				return
+			}
+
+			if e.cfg.sorted {
+				var err error
+
+				if entities, last, err = e.passOrdered(entities, last); err != nil {
+					e.abort(fmt.Errorf("%w: %w", ErrSort, err), in)
+
+					return
+				}
+
+				if len(entities) == 0 {
+					continue
+				}
+			}
+
+			select {
+			case out <- entities:
+			case <-ctx.Done():
+				e.abort(ctx.Err(), in)
+
+				return
+			}
+		}
+	}
+}
+
+// passOrdered returns the entities that follow last, the last entity passed
+// on to the pipeline, in order, along with the new last entity.  The others
+// are added to the sorter, to be merged with the stored body once the encoder
+// is closed; a streaming encoder has already written the entities that they
+// should precede, so it fails instead.
+func (e *Encoder) passOrdered(entities []model.Entity, last model.Entity) ([]model.Entity, model.Entity, error) {
+	i := 0
+	for i < len(entities) && (last == nil || model.Compare(last, entities[i]) <= 0) {
+		last = entities[i]
+		i++
+	}
+
+	if i == len(entities) {
+		return entities, last, nil
+	}
+
+	if e.cfg.streaming {
+		return nil, last, fmt.Errorf("%s %d follows %s %d, which has been written",
+			model.TypeOf(entities[i]), entities[i].GetID(), model.TypeOf(last), last.GetID())
+	}
+
+	if e.sorter == nil {
+		e.sorter = newSorter(e.cfg.store, e.cfg.sortRunSize, e.cfg.precision)
+		e.sorterBBox = model.InitialBoundingBox()
+	}
+
+	ordered := slices.Clone(entities[:i])
+
+	var unordered []model.Entity
+
+	for _, entity := range entities[i:] {
+		if model.Compare(last, entity) <= 0 {
+			ordered = append(ordered, entity)
+			last = entity
+
+			continue
+		}
+
+		if n, ok := entity.(*model.Node); ok {
+			e.sorterBBox.ExpandWithLatLng(n.Lat, n.Lon)
+		}
+
+		unordered = append(unordered, entity)
+	}
+
+	return ordered, last, e.sorter.add(unordered)
+}
+
+// forwardSorted passes the entities of sorted on to out, in batches, until
+// they are exhausted or ctx is done.
+func (e *Encoder) forwardSorted(ctx context.Context, sorted iter.Seq2[model.Entity, error], out chan<- []model.Entity) {
+	defer close(out)
+
+	batch := make([]model.Entity, 0, encoder.EntityLimit)
+
+	send := func() bool {
+		select {
+		case out <- batch:
+			batch = make([]model.Entity, 0, encoder.EntityLimit)
+
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for entity, err := range sorted {
+		if err != nil {
+			e.fail(fmt.Errorf("%w: %w", ErrSort, err))
+
+			return
+		}
+
+		batch = append(batch, entity)
+		if len(batch) == encoder.EntityLimit && !send() {
+			break
+		}
+	}
+
+	if len(batch) > 0 && ctx.Err() == nil {
+		send()
+	}
+
+	// the spilled runs, and so the body, are cut short once ctx is done
+	if ctx.Err() != nil {
+		e.fail(ctx.Err())
+	}
+}
+
+// abort records err and discards anything still sent directly to Entities,
+// until Close closes it.
+func (e *Encoder) abort(err error, in <-chan []model.Entity) {
+	e.fail(err)
+
+	go func() {
+		for range in { //nolint:revive
+		}
+	}()
 }
 
 func (e *Encoder) consumeBBoxes(bboxes <-chan rill.Try[*model.BoundingBox]) {
@@ -134,7 +401,9 @@ Loop:
 			if !ok {
 				break Loop
 			}
-			e.Header.BoundingBox.ExpandWithBoundingBox(bbox.Value)
+			if e.cfg.bbox == nil && !e.cfg.streaming {
+				e.Header.BoundingBox.ExpandWithBoundingBox(bbox.Value)
+			}
 		}
 	}
 }
@@ -149,13 +418,24 @@ Loop:
 				break Loop
 			} else if status.Error != nil {
 				slog.Error("Got status error", "status", status)
-				e.doClose(status.Error)
+				e.fail(fmt.Errorf("%w: %w", ErrWriteBlock, status.Error))
 			}
 		}
 	}
 }
 
-func (e *Encoder) writeHeaderAndBody() {
+// awaitBody waits for the blocks, written straight to the destination by a
+// streaming encoder, to be saved.
+func (e *Encoder) awaitBody() {
+	defer e.closed.Done()
+
+	e.completed.Wait()
+}
+
+// writeHeaderAndBody writes the header, once the bounding box is known, and
+// then the body held in the temporary store, merged with the entities held
+// back by a sorted encoder if there are any.
+func (e *Encoder) writeHeaderAndBody(ctx context.Context) {
 	defer e.closed.Done()
 	defer func() {
 		if err := os.RemoveAll(e.cfg.store); err != nil {
@@ -165,21 +445,62 @@ func (e *Encoder) writeHeaderAndBody() {
 
 	e.completed.Wait()
 
+	if e.sorter != nil {
+		defer func() {
+			if err := e.sorter.close(); err != nil {
+				slog.Error("error removing sorted runs", "error", err)
+			}
+		}()
+
+		if e.cfg.bbox == nil {
+			e.Header.BoundingBox.ExpandWithBoundingBox(e.sorterBBox)
+		}
+	}
+
+	// the stored body is incomplete, so there is nothing worth writing
+	if e.firstErr() != nil {
+		return
+	}
+
 	if err := e.cfg.wrtr.Sync(); err != nil {
-		panic(fmt.Errorf("cannot sync batch: %w", err))
+		e.fail(fmt.Errorf("%w: cannot sync temp store: %w", ErrWriteBlock, err))
+
+		return
+	}
+
+	if _, err := e.cfg.wrtr.Seek(0, io.SeekStart); err != nil {
+		e.fail(fmt.Errorf("%w: cannot seek to beginning of temp store: %w", ErrWriteBlock, err))
+
+		return
 	}
 
-	if offset, err := e.cfg.wrtr.Seek(0, io.SeekStart); err != nil {
-		panic(fmt.Errorf("cannot seek to beginning of file: %w", err))
-	} else if offset != 0 {
-		panic("cannot seek to beginning of file")
+	if err := encoder.SaveHeader(e.wrtr, e.Header, e.cfg.compression, e.cfg.compressionLevel); err != nil {
+		e.fail(fmt.Errorf("%w: %w", ErrWriteHeader, err))
+
+		return
 	}
 
-	if err := encoder.SaveHeader(e.wrtr, e.Header, e.cfg.compression); err != nil {
-		panic(fmt.Sprintf("error writing header: %v", err))
+	if e.sorter != nil {
+		e.writeMerged(ctx)
+
+		return
 	}
 
 	if _, err := io.Copy(e.wrtr, e.cfg.wrtr); err != nil {
-		panic(fmt.Sprintf("error copying entities file: %v", err))
+		e.fail(fmt.Errorf("%w: cannot copy temp store: %w", ErrWriteBlock, err))
+	}
+}
+
+// writeMerged writes the entities of the stored body, which are in order,
+// merged with those held back by the sorter.
+func (e *Encoder) writeMerged(ctx context.Context) {
+	batches := make(chan []model.Entity)
+
+	go e.forwardSorted(ctx, e.sorter.merged(ctx, e.cfg.wrtr), batches)
+
+	for status := range encoder.SavePacked(e.wrtr, e.pack(encoder.CoalesceSorted(batches, encoder.EntityLimit))) {
+		if status.Error != nil {
+			e.fail(fmt.Errorf("%w: %w", ErrWriteBlock, status.Error))
+		}
 	}
 }
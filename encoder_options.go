@@ -8,11 +8,16 @@ import (
 	"time"
 
 	"m4o.io/pbf/v2/internal/encoder"
+	"m4o.io/pbf/v2/model"
 )
 
 const (
 	DefaultBlobCompression = encoder.ZLIB
 
+	// DefaultCompressionLevel selects the default level of the compression
+	// algorithm.
+	DefaultCompressionLevel = encoder.DefaultCompressionLevel
+
 	tempFileName = "entities.pbf"
 )
 
@@ -25,12 +30,24 @@ var (
 
 // encoderOptions provides optional configuration parameters for Encoder construction.
 type encoderOptions struct {
-	compression encoder.BlobCompression
-	nCPU        uint16 // the number of CPUs to use for background processing
+	compression      encoder.BlobCompression
+	compressionLevel int    // the level of the compression algorithm
+	nCPU             uint16 // the number of CPUs to use for background processing
+
+	precision encoder.Precision // the units of coordinates and timestamps
 
 	store string
 	wrtr  *os.File
 
+	streaming bool               // write blocks straight to the destination
+	bbox      *model.BoundingBox // the header bounding box, if not computed
+
+	locationsOnWays bool // write the coordinates of the nodes of ways
+
+	sorted      bool // order entities by type, then ID
+	sortRunSize int  // the max number of entities sorted in memory
+	inputOrder  bool // keep entities in the order they are encoded
+
 	requiredFeatures                 []string
 	optionalFeatures                 []string
 	writingProgram                   string
@@ -51,6 +68,56 @@ func WithCompression(compression encoder.BlobCompression) EncoderOption {
 	}
 }
 
+// WithCompressionLevel specifies the level of the compression algorithm,
+// trading CPU for size.  Valid levels are -2 to 9 for ZLIB, as for
+// compress/zlib; 1 to 22 for ZSTD and 0 to 9 for LZMA, as for the zstd and xz
+// commands; and 0 or more for LZ4, where 0 is fastest.  Levels are ignored by
+// RAW.  NewEncoder fails with ErrInvalidCompressionLevel for other levels.
+// The default is DefaultCompressionLevel.
+func WithCompressionLevel(level int) EncoderOption {
+	return func(o *encoderOptions) {
+		o.compressionLevel = level
+	}
+}
+
+// WithEncoderNCpus lets you set the number of CPUs to use for encoding and
+// compressing blocks.
+func WithEncoderNCpus(n uint16) EncoderOption {
+	return func(o *encoderOptions) {
+		o.nCPU = n
+	}
+}
+
+// WithGranularity sets the size, in nano-degrees, of the units in which
+// coordinates are encoded.  Coarser granularities write smaller, lossy
+// files; for example, 10000 keeps five decimal places.  The default is 100.
+func WithGranularity(granularity int32) EncoderOption {
+	return func(o *encoderOptions) {
+		o.precision.Granularity = granularity
+	}
+}
+
+// WithBlockOffsets writes, as the lat/lon offsets of each block, the
+// smallest coordinates of its nodes, or of the locations of its ways, rounded
+// down to the granularity.  Coordinates are delta coded, so this shrinks the
+// varint of the first coordinate pair of each block.  The default is no
+// offset.
+func WithBlockOffsets() EncoderOption {
+	return func(o *encoderOptions) {
+		o.precision.BlockOffsets = true
+	}
+}
+
+// WithDateGranularity sets the size, in milliseconds, of the units in which
+// timestamps are encoded.  The timestamps of ways and relations are int32s,
+// so NewEncoder fails with ErrInvalidGranularity for granularities too fine
+// to hold the current time.  The default is 1000.
+func WithDateGranularity(granularity int32) EncoderOption {
+	return func(o *encoderOptions) {
+		o.precision.DateGranularity = granularity
+	}
+}
+
 // WithStorePath lets you specify where to temporarily store entities.
 func WithStorePath(path string) EncoderOption {
 	return func(o *encoderOptions) {
@@ -58,6 +125,74 @@ func WithStorePath(path string) EncoderOption {
 	}
 }
 
+// WithStreaming writes the header as soon as the encoder is created, with
+// bbox as its bounding box or none if bbox is nil, and then writes blocks
+// straight to the destination.  No temporary store is used, so the
+// destination need not be seekable and WithStorePath is ignored.  By default,
+// the header's bounding box is computed from the encoded nodes, which
+// requires the blocks to be held in a temporary store until the encoder is
+// closed.  Combined with WithSorted, the entities must be encoded in order.
+func WithStreaming(bbox *model.BoundingBox) EncoderOption {
+	return func(o *encoderOptions) {
+		o.streaming = true
+		o.bbox = bbox
+	}
+}
+
+// WithBoundingBox sets the bounding box of the header, rather than computing
+// it from the encoded nodes.  Unlike WithStreaming, the blocks are still held
+// in the temporary store until the encoder is closed.
+func WithBoundingBox(bbox *model.BoundingBox) EncoderOption {
+	return func(o *encoderOptions) {
+		o.bbox = bbox
+	}
+}
+
+// WithLocationsOnWays writes, for every way whose Lats and Lons hold a
+// coordinate pair for each of its nodes, those coordinates, and declares the
+// LocationsOnWays required feature.  Readers can then build the geometry of
+// ways without looking up their nodes.
+func WithLocationsOnWays() EncoderOption {
+	return func(o *encoderOptions) {
+		o.locationsOnWays = true
+	}
+}
+
+// WithSorted writes nodes, then ways, then relations, each in ascending ID
+// order, and declares the Sort.Type_then_ID optional feature.  Entities that
+// are encoded in order are written as they arrive, so sorted input costs no
+// more than with an unsorted encoder.  The others are sorted in runs that are
+// spilled to a directory in the temporary store, see WithStorePath and
+// WithSortRunSize, and merged with the written entities once the encoder is
+// closed.  A streaming encoder cannot merge what it has already written to
+// the destination, so Encode and Close fail with ErrSort once an entity is
+// encoded out of order.
+func WithSorted() EncoderOption {
+	return func(o *encoderOptions) {
+		o.sorted = true
+	}
+}
+
+// WithInputOrder writes the entities in the order they are encoded, in blocks
+// of consecutive entities of a type, rather than batching them by type as
+// they arrive.  Unlike WithSorted, nothing is held back, sorted or checked, so
+// entities that are already sorted by type, then ID can be written at no
+// extra cost; declare so with WithOptionalFeatures(SortTypeThenID).
+func WithInputOrder() EncoderOption {
+	return func(o *encoderOptions) {
+		o.inputOrder = true
+	}
+}
+
+// WithSortRunSize sets the number of entities a sorted encoder holds in
+// memory before spilling them to the temporary store.  The default is
+// DefaultSortRunSize.
+func WithSortRunSize(n int) EncoderOption {
+	return func(o *encoderOptions) {
+		o.sortRunSize = n
+	}
+}
+
 // WithRequiredFeatures sets the required features of the PBF header.
 func WithRequiredFeatures(features ...string) EncoderOption {
 	return func(o *encoderOptions) {
@@ -112,7 +247,11 @@ func WithOsmosisReplicationBaseURL(url string) EncoderOption {
 
 // defaultEncoderConfig provides a default configuration for encoders.
 var defaultEncoderConfig = encoderOptions{
-	compression: DefaultBlobCompression,
+	compression:      DefaultBlobCompression,
+	compressionLevel: DefaultCompressionLevel,
+	nCPU:             DefaultNCpu(),
+	precision:        encoder.DefaultPrecision,
+	sortRunSize:      DefaultSortRunSize,
 	requiredFeatures: []string{
 		"OsmSchema-V0.6",
 		"DenseNodes",
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"math/rand/v2"
+	"os"
 	"path/filepath"
 	"slices"
 	"testing"
 	"time"
 
+	"m4o.io/pbf/v2/internal/encoder"
 	"m4o.io/pbf/v2/model"
 )
 
@@ -98,7 +102,9 @@ func TestRoundTripPreservesDenseNodeVisibility(t *testing.T) {
 	if err := enc.EncodeBatch(nodes); err != nil {
 		t.Fatalf("encode nodes: %v", err)
 	}
-	enc.Close()
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
 
 	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
 	if err != nil {
@@ -138,3 +144,753 @@ func TestRoundTripPreservesDenseNodeVisibility(t *testing.T) {
 		t.Fatalf("node 1002 visibility mismatch: got %t want %t", got, true)
 	}
 }
+
+func TestStreamingEncoderWritesHeaderImmediately(t *testing.T) {
+	bbox := &model.BoundingBox{Top: 42.3, Left: -72.0, Bottom: 42.1, Right: -71.8}
+
+	// the store path is ignored since no temporary store is needed
+	invalidStore := filepath.Join(t.TempDir(), "missing", "store")
+
+	var encoded bytes.Buffer
+
+	enc, err := NewEncoder(&encoded, WithStreaming(bbox), WithStorePath(invalidStore))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	if encoded.Len() == 0 {
+		t.Fatal("expected header to be written before any entity is encoded")
+	}
+
+	nodes := []model.Entity{
+		&model.Node{ID: 1, Lat: 42.2, Lon: -71.9, Tags: map[string]string{}, Info: &model.Info{Visible: true}},
+		&model.Node{ID: 2, Lat: 42.25, Lon: -71.85, Tags: map[string]string{}, Info: &model.Info{Visible: true}},
+	}
+
+	if err := enc.EncodeBatch(nodes); err != nil {
+		t.Fatalf("encode nodes: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+	defer dec.Close()
+
+	if !bbox.EqualWithin(dec.Header.BoundingBox, model.E7) {
+		t.Fatalf("bounding box mismatch: got %s want %s", dec.Header.BoundingBox, bbox)
+	}
+
+	var ids []model.ID
+
+	for e, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		ids = append(ids, e.GetID())
+	}
+
+	if !slices.Equal(ids, []model.ID{1, 2}) {
+		t.Fatalf("decoded IDs mismatch: got %v", ids)
+	}
+}
+
+func TestStreamingEncoderWithoutBoundingBox(t *testing.T) {
+	var encoded bytes.Buffer
+
+	enc, err := NewEncoder(&encoded, WithStreaming(nil))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+	defer dec.Close()
+
+	if dec.Header.BoundingBox != nil {
+		t.Fatalf("expected no bounding box, got %s", dec.Header.BoundingBox)
+	}
+}
+
+// failingWriter fails every write once limit bytes have been written.
+type failingWriter struct {
+	limit   int
+	written int
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		return 0, errDiskFull
+	}
+
+	w.written += len(p)
+
+	return len(p), nil
+}
+
+func TestCloseReturnsHeaderWriteError(t *testing.T) {
+	enc, err := NewEncoder(&failingWriter{})
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	err = enc.Close()
+	if !errors.Is(err, ErrWriteHeader) || !errors.Is(err, errDiskFull) {
+		t.Fatalf("expected ErrWriteHeader wrapping errDiskFull, got: %v", err)
+	}
+
+	if again := enc.Close(); again != err {
+		t.Fatalf("expected repeated Close to return the first error, got: %v", again)
+	}
+}
+
+func TestNewEncoderReturnsStreamingHeaderWriteError(t *testing.T) {
+	_, err := NewEncoder(&failingWriter{}, WithStreaming(nil))
+	if !errors.Is(err, ErrWriteHeader) {
+		t.Fatalf("expected ErrWriteHeader, got: %v", err)
+	}
+}
+
+func TestEncodeBatchReturnsBlockWriteError(t *testing.T) {
+	var header bytes.Buffer
+
+	probe, err := NewEncoder(&header, WithStreaming(nil))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+	if err := probe.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	// let the header through, failing every block
+	w := &failingWriter{limit: header.Len()}
+
+	enc, err := NewEncoder(w, WithStreaming(nil))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	nodes := make([]model.Entity, 0, 1000)
+	for i := range cap(nodes) {
+		nodes = append(nodes, &model.Node{ID: model.ID(i), Tags: map[string]string{}, Info: &model.Info{Visible: true}})
+	}
+
+	// enough blocks are filled for the pipeline to fail before we give up
+	for range 1000 {
+		if err = enc.EncodeBatch(nodes); err != nil {
+			break
+		}
+	}
+
+	if !errors.Is(err, ErrWriteBlock) || !errors.Is(err, errDiskFull) {
+		t.Fatalf("expected ErrWriteBlock wrapping errDiskFull, got: %v", err)
+	}
+
+	if err := enc.Close(); !errors.Is(err, ErrWriteBlock) {
+		t.Fatalf("expected ErrWriteBlock from Close, got: %v", err)
+	}
+}
+
+func TestCancelledEncoderStopsAndRemovesTempStore(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "store")
+	if err := os.Mkdir(store, 0o755); err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	enc, err := NewEncoderContext(ctx, &bytes.Buffer{}, WithStorePath(store))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	node := &model.Node{ID: 1, Tags: map[string]string{}, Info: &model.Info{Visible: true}}
+	if err := enc.Encode(node); err != nil {
+		t.Fatalf("encode node: %v", err)
+	}
+
+	cancel()
+
+	// the temp store is removed without waiting for Close
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(store); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("temp store still exists after cancellation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := enc.Encode(node); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled from Encode, got: %v", err)
+	}
+
+	if err := enc.Close(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled from Close, got: %v", err)
+	}
+}
+
+func TestCompressionLevelsRoundTrip(t *testing.T) {
+	test_cases := []struct {
+		compression encoder.BlobCompression
+		level       int
+	}{
+		{encoder.RAW, 5},
+		{encoder.ZLIB, 9},
+		{encoder.ZLIB, 0},
+		{encoder.LZMA, 0},
+		{encoder.LZMA, 9},
+		{encoder.LZ4, 9},
+		{encoder.ZSTD, 1},
+		{encoder.ZSTD, 19},
+	}
+
+	nodes := make([]model.Entity, 0, 100)
+	for i := range cap(nodes) {
+		nodes = append(nodes, &model.Node{ID: model.ID(i), Tags: map[string]string{}, Info: &model.Info{Visible: true}})
+	}
+
+	for _, tc := range test_cases {
+		t.Run(fmt.Sprintf("%s/%d", tc.compression, tc.level), func(t *testing.T) {
+			var encoded bytes.Buffer
+
+			enc, err := NewEncoder(&encoded,
+				WithCompression(tc.compression),
+				WithCompressionLevel(tc.level),
+				WithEncoderNCpus(2))
+			if err != nil {
+				t.Fatalf("create encoder: %v", err)
+			}
+
+			if err := enc.EncodeBatch(nodes); err != nil {
+				t.Fatalf("encode nodes: %v", err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatalf("close encoder: %v", err)
+			}
+
+			dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
+			if err != nil {
+				t.Fatalf("create decoder: %v", err)
+			}
+
+			var n int
+
+			for _, err := range dec.All() {
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+
+				n++
+			}
+
+			if n != len(nodes) {
+				t.Fatalf("decoded %d nodes, want %d", n, len(nodes))
+			}
+		})
+	}
+}
+
+func TestNewEncoderFailsOnInvalidCompressionLevel(t *testing.T) {
+	for _, c := range []encoder.BlobCompression{encoder.ZLIB, encoder.LZMA, encoder.ZSTD} {
+		_, err := NewEncoder(&bytes.Buffer{}, WithCompression(c), WithCompressionLevel(42))
+		if !errors.Is(err, ErrInvalidCompressionLevel) {
+			t.Fatalf("%s: expected ErrInvalidCompressionLevel, got: %v", c, err)
+		}
+	}
+}
+
+func TestRoundTripWithCoarsePrecision(t *testing.T) {
+	ts := time.Date(2024, 5, 17, 12, 34, 56, 0, time.UTC)
+	node := &model.Node{
+		ID:   7,
+		Lat:  51.7654321,
+		Lon:  -0.2345678,
+		Tags: map[string]string{},
+		Info: &model.Info{Version: 1, Timestamp: ts, Visible: true},
+	}
+
+	var encoded bytes.Buffer
+
+	enc, err := NewEncoder(&encoded,
+		WithGranularity(10_000),
+		WithBlockOffsets(),
+		WithDateGranularity(60_000))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	if err := enc.Encode(node); err != nil {
+		t.Fatalf("encode node: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+
+	var got *model.Node
+
+	for n, err := range dec.Nodes() {
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		got = n
+	}
+
+	if got == nil {
+		t.Fatal("missing node from decoded output")
+	}
+	if !got.Lat.EqualWithin(51.76543, model.E5) || !got.Lon.EqualWithin(-0.23457, model.E5) {
+		t.Fatalf("coordinates mismatch: got (%v, %v)", float64(got.Lat), float64(got.Lon))
+	}
+	if want := ts.Truncate(time.Minute); !got.Info.Timestamp.Equal(want) {
+		t.Fatalf("timestamp mismatch: got %s want %s", got.Info.Timestamp, want)
+	}
+}
+
+func TestRoundTripWithDateGranularity(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 34, 57, 0, time.UTC)
+	info := &model.Info{Version: 1, Timestamp: ts, Visible: true}
+	entities := []model.Entity{
+		&model.Node{ID: 1, Tags: map[string]string{}, Info: info},
+		&model.Way{ID: 1, Tags: map[string]string{}, NodeIDs: []model.ID{1}, Info: info},
+		&model.Relation{ID: 1, Tags: map[string]string{}, Members: []model.Member{{ID: 1, Type: model.WAY}}, Info: info},
+	}
+
+	var encoded bytes.Buffer
+
+	enc, err := NewEncoder(&encoded, WithDateGranularity(2000))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	if err := enc.EncodeBatch(entities); err != nil {
+		t.Fatalf("encode batch: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+
+	n := 0
+
+	for e, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		if want := ts.Truncate(2 * time.Second); !e.GetInfo().Timestamp.Equal(want) {
+			t.Fatalf("%T timestamp mismatch: got %s want %s", e, e.GetInfo().Timestamp, want)
+		}
+
+		n++
+	}
+
+	if n != len(entities) {
+		t.Fatalf("decoded %d entities, want %d", n, len(entities))
+	}
+}
+
+func TestNewEncoderFailsOnInvalidGranularity(t *testing.T) {
+	for _, opt := range []EncoderOption{WithGranularity(0), WithDateGranularity(-1), WithDateGranularity(1)} {
+		if _, err := NewEncoder(&bytes.Buffer{}, opt); !errors.Is(err, ErrInvalidGranularity) {
+			t.Fatalf("expected ErrInvalidGranularity, got: %v", err)
+		}
+	}
+}
+
+func TestSortedEncoderOrdersByTypeThenID(t *testing.T) {
+	var entities []model.Entity
+
+	for e, err := range openSample(t).All() {
+		if err != nil {
+			t.Fatalf("decode sample: %v", err)
+		}
+
+		entities = append(entities, e)
+	}
+
+	rand.New(rand.NewPCG(1, 2)).Shuffle(len(entities), func(i, j int) {
+		entities[i], entities[j] = entities[j], entities[i]
+	})
+
+	var encoded bytes.Buffer
+
+	// a small run size spills several runs to the temp store
+	enc, err := NewEncoder(&encoded, WithSorted(), WithSortRunSize(50), WithStorePath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	for batch := range slices.Chunk(entities, 30) {
+		if err := enc.EncodeBatch(batch); err != nil {
+			t.Fatalf("encode batch: %v", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+
+	if !slices.Contains(dec.Header.OptionalFeatures, "Sort.Type_then_ID") {
+		t.Fatalf("missing Sort.Type_then_ID from optional features %v", dec.Header.OptionalFeatures)
+	}
+
+	var decoded []model.Entity
+
+	for e, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		decoded = append(decoded, e)
+	}
+
+	if len(decoded) != len(entities) {
+		t.Fatalf("decoded %d entities, want %d", len(decoded), len(entities))
+	}
+
+	if !slices.IsSortedFunc(decoded, model.Compare) {
+		t.Fatal("decoded entities are not ordered by type, then ID")
+	}
+}
+
+func TestSortedEncoderMergesEntitiesEncodedOutOfOrder(t *testing.T) {
+	var sorted []model.Entity
+
+	for e, err := range openSample(t).All() {
+		if err != nil {
+			t.Fatalf("decode sample: %v", err)
+		}
+
+		sorted = append(sorted, e)
+	}
+
+	slices.SortFunc(sorted, model.Compare)
+
+	// a node and a way arrive after the entities they precede
+	entities := slices.Concat(sorted[:5], sorted[6:300], sorted[301:], []model.Entity{sorted[300], sorted[5]})
+
+	var encoded bytes.Buffer
+
+	enc, err := NewEncoder(&encoded, WithSorted(), WithSortRunSize(1), WithStorePath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	if err := enc.EncodeBatch(entities); err != nil {
+		t.Fatalf("encode batch: %v", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	if enc.sorter == nil || len(enc.sorter.runs) != 2 {
+		t.Fatal("expected the entities encoded out of order, and only them, to be spilled")
+	}
+
+	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()), WithOrdered())
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+
+	var ids []model.ID
+
+	for e, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		ids = append(ids, e.GetID())
+	}
+
+	want := make([]model.ID, len(sorted))
+	for i, e := range sorted {
+		want[i] = e.GetID()
+	}
+
+	if !slices.Equal(ids, want) {
+		t.Fatalf("decoded IDs mismatch: got %v want %v", ids, want)
+	}
+}
+
+func TestSortedStreamingEncoderWritesOrderedEntitiesBeforeClose(t *testing.T) {
+	var header bytes.Buffer
+
+	probe, err := NewEncoder(&header, WithStreaming(nil), WithSorted())
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+	if err := probe.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	// let the header through, failing every block
+	w := &failingWriter{limit: header.Len()}
+
+	enc, err := NewEncoder(w, WithStreaming(nil), WithSorted())
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	nodes := make([]model.Entity, 1000)
+
+	// blocks are only written, and so fail, before Close if the entities are
+	// not held back to be sorted
+	for i := range 1000 {
+		for j := range nodes {
+			nodes[j] = &model.Node{ID: model.ID(i*len(nodes) + j), Tags: map[string]string{}, Info: &model.Info{Visible: true}}
+		}
+
+		if err = enc.EncodeBatch(slices.Clone(nodes)); err != nil {
+			break
+		}
+	}
+
+	if !errors.Is(err, ErrWriteBlock) {
+		t.Fatalf("expected ErrWriteBlock before Close, got: %v", err)
+	}
+
+	_ = enc.Close()
+}
+
+func TestSortedStreamingEncoderFailsOnEntitiesOutOfOrder(t *testing.T) {
+	enc, err := NewEncoder(&bytes.Buffer{}, WithStreaming(nil), WithSorted())
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	_ = enc.EncodeBatch([]model.Entity{&model.Way{ID: 1}, &model.Node{ID: 1}})
+
+	if err := enc.Close(); !errors.Is(err, ErrSort) {
+		t.Fatalf("expected ErrSort, got: %v", err)
+	}
+}
+
+func TestInputOrderEncoderKeepsEntityOrder(t *testing.T) {
+	entities := []model.Entity{
+		&model.Node{ID: 3, Tags: map[string]string{}, Info: &model.Info{Visible: true}},
+		&model.Node{ID: 1, Tags: map[string]string{}, Info: &model.Info{Visible: true}},
+		&model.Way{ID: 2, Tags: map[string]string{}, NodeIDs: []model.ID{1, 3}, Info: &model.Info{Visible: true}},
+		&model.Node{ID: 2, Tags: map[string]string{}, Info: &model.Info{Visible: true}},
+	}
+
+	var encoded bytes.Buffer
+
+	enc, err := NewEncoder(&encoded, WithInputOrder(), WithStorePath(t.TempDir()))
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	for _, e := range entities {
+		if err := enc.Encode(e); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	if enc.sorter != nil {
+		t.Fatal("expected no entities to be held back")
+	}
+
+	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()), WithOrdered())
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+
+	if slices.Contains(dec.Header.OptionalFeatures, SortTypeThenID) {
+		t.Fatalf("unexpected %s in optional features %v", SortTypeThenID, dec.Header.OptionalFeatures)
+	}
+
+	var decoded []model.Entity
+
+	for e, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		decoded = append(decoded, e)
+	}
+
+	if !slices.EqualFunc(decoded, entities, func(a, b model.Entity) bool { return model.Compare(a, b) == 0 }) {
+		t.Fatalf("decoded entities out of order: got %v want %v", decoded, entities)
+	}
+}
+
+func TestSorterRemovesSpilledRuns(t *testing.T) {
+	store := t.TempDir()
+	s := newSorter(store, 2, encoder.DefaultPrecision)
+
+	nodes := []model.Entity{
+		&model.Node{ID: 3, Tags: map[string]string{}, Info: &model.Info{Visible: true}},
+		&model.Node{ID: 1, Tags: map[string]string{}, Info: &model.Info{Visible: true}},
+		&model.Way{ID: 2, Tags: map[string]string{}, Info: &model.Info{Visible: true}},
+		&model.Node{ID: 2, Tags: map[string]string{}, Info: &model.Info{Visible: true}},
+	}
+
+	if err := s.add(nodes); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	var ids []model.ID
+
+	for e, err := range s.sorted(context.Background()) {
+		if err != nil {
+			t.Fatalf("merge: %v", err)
+		}
+
+		ids = append(ids, e.GetID())
+	}
+
+	if want := []model.ID{1, 2, 3, 2}; !slices.Equal(ids, want) {
+		t.Fatalf("merged IDs %v, want %v", ids, want)
+	}
+
+	if err := s.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if left, _ := os.ReadDir(store); len(left) != 0 {
+		t.Fatalf("spilled runs left in store: %v", left)
+	}
+}
+
+func TestLocationsOnWaysRoundTrip(t *testing.T) {
+	located := &model.Way{
+		ID:      1,
+		Tags:    map[string]string{},
+		Info:    &model.Info{Visible: true},
+		NodeIDs: []model.ID{10, 11, 12},
+		Lats:    []model.Degrees{51.5, 51.50001, 51.49998},
+		Lons:    []model.Degrees{-0.12, -0.11999, -0.12003},
+	}
+	unlocated := &model.Way{
+		ID:      2,
+		Tags:    map[string]string{},
+		Info:    &model.Info{Visible: true},
+		NodeIDs: []model.ID{10, 12},
+	}
+
+	var encoded bytes.Buffer
+
+	enc, err := NewEncoder(&encoded, WithLocationsOnWays())
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	if err := enc.EncodeBatch([]model.Entity{located, unlocated}); err != nil {
+		t.Fatalf("encode ways: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+
+	if !slices.Contains(dec.Header.RequiredFeatures, "LocationsOnWays") {
+		t.Fatalf("missing LocationsOnWays from required features %v", dec.Header.RequiredFeatures)
+	}
+
+	ways := map[model.ID]*model.Way{}
+
+	for w, err := range dec.Ways() {
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		ways[w.ID] = w
+	}
+
+	got := ways[located.ID]
+	if got == nil || len(got.Lats) != len(located.NodeIDs) || len(got.Lons) != len(located.NodeIDs) {
+		t.Fatalf("expected a location for every node of way 1, got: %+v", got)
+	}
+
+	for i := range located.Lats {
+		if !got.Lats[i].EqualWithin(located.Lats[i], model.E7) || !got.Lons[i].EqualWithin(located.Lons[i], model.E7) {
+			t.Fatalf("location %d mismatch: got (%v, %v)", i, float64(got.Lats[i]), float64(got.Lons[i]))
+		}
+	}
+
+	if got := ways[unlocated.ID]; got == nil || got.Lats != nil || got.Lons != nil {
+		t.Fatalf("expected way 2 without locations, got: %+v", got)
+	}
+}
+
+func TestEncoderOmitsLocationsOnWaysByDefault(t *testing.T) {
+	way := &model.Way{
+		ID:      1,
+		Tags:    map[string]string{},
+		Info:    &model.Info{Visible: true},
+		NodeIDs: []model.ID{10, 11},
+		Lats:    []model.Degrees{51.5, 51.6},
+		Lons:    []model.Degrees{-0.1, -0.2},
+	}
+
+	var encoded bytes.Buffer
+
+	enc, err := NewEncoder(&encoded)
+	if err != nil {
+		t.Fatalf("create encoder: %v", err)
+	}
+
+	if err := enc.Encode(way); err != nil {
+		t.Fatalf("encode way: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("close encoder: %v", err)
+	}
+
+	dec, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
+	if err != nil {
+		t.Fatalf("create decoder: %v", err)
+	}
+
+	if slices.Contains(dec.Header.RequiredFeatures, "LocationsOnWays") {
+		t.Fatalf("unexpected LocationsOnWays in required features %v", dec.Header.RequiredFeatures)
+	}
+
+	for w, err := range dec.Ways() {
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		if w.Lats != nil || w.Lons != nil {
+			t.Fatalf("expected way without locations, got: %+v", w)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package geom assembles the geometries of ways and multipolygon relations
+from the locations of their nodes.
+
+The locations are taken from the ways themselves when the input declares
+LocationsOnWays, and otherwise looked up with a Locator, such as one of the
+stores of the locations package.  Closed rings are oriented as RFC 7946
+requires of GeoJSON: outer rings counterclockwise and inner rings clockwise.
+Geometries that cannot be assembled are reported with an *Error that wraps
+one of the sentinel errors below.
+*/
+package geom
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/golang/geo/s2"
+
+	"m4o.io/pbf/v2/model"
+)
+
+var (
+	// ErrMissingLocation is returned when the location of a node is not
+	// known.
+	ErrMissingLocation = errors.New("missing node location")
+	// ErrTooFewNodes is returned when a way has too few nodes for its
+	// geometry.
+	ErrTooFewNodes = errors.New("too few nodes")
+	// ErrNotClosed is returned when a way, or a ring joined from the ways of
+	// a multipolygon, does not end where it starts.
+	ErrNotClosed = errors.New("not closed")
+	// ErrInvalidRing is returned when a ring has repeated or antipodal
+	// points, or crosses itself.
+	ErrInvalidRing = errors.New("invalid ring")
+	// ErrMissingWay is returned when a way member of a multipolygon is not
+	// known.
+	ErrMissingWay = errors.New("missing way")
+	// ErrNotMultipolygon is returned when a relation is neither of type
+	// multipolygon nor boundary.
+	ErrNotMultipolygon = errors.New("not a multipolygon")
+)
+
+// Locator looks up the location of nodes.  It is satisfied by the stores of
+// the locations package.
+type Locator interface {
+	// Lookup returns the location of the node id, and whether it is known.
+	Lookup(id model.ID) (lat, lon model.Degrees, ok bool)
+}
+
+// Error describes why the geometry of an entity could not be assembled.
+type Error struct {
+	Type  model.EntityType // the type of the entity
+	ID    model.ID         // the ID of the entity
+	Ways  []model.ID       // the ways at fault, such as those of a broken ring
+	Nodes []model.ID       // the nodes at fault, such as the ends of an open ring
+	Err   error            // one of the sentinel errors of this package
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %d: %v", strings.ToLower(e.Type.String()), e.ID, e.Err)
+
+	if len(e.Ways) > 0 {
+		fmt.Fprintf(&b, ", ways %v", e.Ways)
+	}
+
+	if len(e.Nodes) > 0 {
+		fmt.Fprintf(&b, ", nodes %v", e.Nodes)
+	}
+
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WayLineString returns the line through the nodes of w, located with locs
+// unless w holds their locations.
+func WayLineString(w *model.Way, locs Locator) (model.LineString, error) {
+	if len(w.NodeIDs) < 2 {
+		return nil, &Error{Type: model.WAY, ID: w.ID, Err: ErrTooFewNodes}
+	}
+
+	points, missing := locate(w, locs)
+	if len(missing) > 0 {
+		return nil, &Error{Type: model.WAY, ID: w.ID, Nodes: missing, Err: ErrMissingLocation}
+	}
+
+	return points, nil
+}
+
+// WayPolygon returns the area enclosed by the closed way w, located with
+// locs unless w holds the locations of its nodes.
+func WayPolygon(w *model.Way, locs Locator) (*model.Polygon, error) {
+	ids := w.NodeIDs
+
+	if len(ids) < 4 {
+		return nil, &Error{Type: model.WAY, ID: w.ID, Err: ErrTooFewNodes}
+	}
+
+	if ids[0] != ids[len(ids)-1] {
+		return nil, &Error{Type: model.WAY, ID: w.ID, Nodes: []model.ID{ids[0], ids[len(ids)-1]}, Err: ErrNotClosed}
+	}
+
+	points, missing := locate(w, locs)
+	if len(missing) > 0 {
+		return nil, &Error{Type: model.WAY, ID: w.ID, Nodes: missing, Err: ErrMissingLocation}
+	}
+
+	outer, _, err := newRing(points[:len(points)-1])
+	if err != nil {
+		return nil, &Error{Type: model.WAY, ID: w.ID, Err: err}
+	}
+
+	return &model.Polygon{Outer: outer}, nil
+}
+
+// IsClosed reports whether the way w ends where it starts.
+func IsClosed(w *model.Way) bool {
+	return len(w.NodeIDs) > 2 && w.NodeIDs[0] == w.NodeIDs[len(w.NodeIDs)-1]
+}
+
+// locate returns the locations of the nodes of w, and the IDs of the nodes
+// whose locations are missing.
+func locate(w *model.Way, locs Locator) (model.LineString, []model.ID) {
+	if len(w.Lats) == len(w.NodeIDs) && len(w.Lons) == len(w.NodeIDs) {
+		points := make(model.LineString, len(w.NodeIDs))
+		for i := range points {
+			points[i] = model.Point{Lat: w.Lats[i], Lon: w.Lons[i]}
+		}
+
+		return points, nil
+	}
+
+	if locs == nil {
+		return nil, w.NodeIDs
+	}
+
+	var missing []model.ID
+
+	points := make(model.LineString, len(w.NodeIDs))
+
+	for i, id := range w.NodeIDs {
+		lat, lon, ok := locs.Lookup(id)
+		if !ok {
+			missing = append(missing, id)
+
+			continue
+		}
+
+		points[i] = model.Point{Lat: lat, Lon: lon}
+	}
+
+	return points, missing
+}
+
+// newRing returns the ring through points, which are not repeated at its
+// end, oriented counterclockwise, together with its loop.
+func newRing(points []model.Point) (model.Ring, *s2.Loop, error) {
+	if len(points) < 3 {
+		return nil, nil, fmt.Errorf("%w: %d points", ErrInvalidRing, len(points))
+	}
+
+	vertices := make([]s2.Point, len(points))
+	for i, p := range points {
+		vertices[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(float64(p.Lat), float64(p.Lon)))
+	}
+
+	loop := s2.LoopFromPoints(vertices)
+	if err := loop.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidRing, err)
+	}
+
+	if i, j, ok := selfIntersection(vertices); ok {
+		return nil, nil, fmt.Errorf("%w: edges %d and %d cross", ErrInvalidRing, i, j)
+	}
+
+	ring := model.Ring(points)
+
+	// a clockwise ring encloses more than half of the sphere
+	if !loop.IsNormalized() {
+		ring = reversed(ring)
+		loop.Invert()
+	}
+
+	return ring, loop, nil
+}
+
+// selfIntersection returns the first pair of non-adjacent edges of the loop
+// through vertices that cross.
+func selfIntersection(vertices []s2.Point) (int, int, bool) {
+	n := len(vertices)
+
+	for i := range n {
+		a, b := vertices[i], vertices[(i+1)%n]
+
+		for j := i + 2; j < n; j++ {
+			// the last edge is adjacent to the first
+			if i == 0 && j == n-1 {
+				continue
+			}
+
+			if s2.CrossingSign(a, b, vertices[j], vertices[(j+1)%n]) != s2.DoNotCross {
+				return i, j, true
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// reversed returns a copy of s in the opposite order.
+func reversed[S ~[]E, E any](s S) S {
+	r := slices.Clone(s)
+	slices.Reverse(r)
+
+	return r
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geom_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2/geom"
+	"m4o.io/pbf/v2/locations"
+	"m4o.io/pbf/v2/model"
+)
+
+// grid locates node 10*row+col at (row, col) degrees.
+type grid struct{}
+
+func (grid) Lookup(id model.ID) (lat, lon model.Degrees, ok bool) {
+	if id < 0 || id >= 100 {
+		return 0, 0, false
+	}
+
+	return model.Degrees(id / 10), model.Degrees(id % 10), true
+}
+
+func way(id model.ID, nodeIDs ...model.ID) *model.Way {
+	return &model.Way{ID: id, Tags: map[string]string{}, NodeIDs: nodeIDs}
+}
+
+func multipolygon(id model.ID, ways ...model.ID) *model.Relation {
+	r := &model.Relation{ID: id, Tags: map[string]string{"type": "multipolygon"}}
+	for _, w := range ways {
+		r.Members = append(r.Members, model.Member{ID: w, Type: model.WAY, Role: "outer"})
+	}
+
+	return r
+}
+
+func lookup(ways ...*model.Way) geom.WayLookup {
+	byID := map[model.ID]*model.Way{}
+	for _, w := range ways {
+		byID[w.ID] = w
+	}
+
+	return func(id model.ID) (*model.Way, bool) {
+		w, ok := byID[id]
+
+		return w, ok
+	}
+}
+
+// signedArea is positive for counterclockwise rings.
+func signedArea(r model.Ring) float64 {
+	var a float64
+
+	for i := range r {
+		p, q := r[i], r[(i+1)%len(r)]
+		a += float64(p.Lon*q.Lat - q.Lon*p.Lat)
+	}
+
+	return a / 2
+}
+
+func TestWayLineString(t *testing.T) {
+	store := locations.NewSparseMap()
+	require.NoError(t, store.Set(1, 51.5, -0.1))
+	require.NoError(t, store.Set(2, 51.6, -0.2))
+
+	line, err := geom.WayLineString(way(7, 1, 2), store)
+	require.NoError(t, err)
+	assert.Equal(t, model.LineString{{Lat: 51.5, Lon: -0.1}, {Lat: 51.6, Lon: -0.2}}, line)
+
+	_, err = geom.WayLineString(way(7, 1, 2, 3), store)
+
+	var gerr *geom.Error
+	require.ErrorAs(t, err, &gerr)
+	assert.ErrorIs(t, err, geom.ErrMissingLocation)
+	assert.Equal(t, model.ID(7), gerr.ID)
+	assert.Equal(t, []model.ID{3}, gerr.Nodes)
+}
+
+func TestWayLineStringUsesLocationsOnWays(t *testing.T) {
+	w := way(7, 1, 2)
+	w.Lats = []model.Degrees{1, 2}
+	w.Lons = []model.Degrees{3, 4}
+
+	line, err := geom.WayLineString(w, nil)
+	require.NoError(t, err)
+	assert.Equal(t, model.LineString{{Lat: 1, Lon: 3}, {Lat: 2, Lon: 4}}, line)
+}
+
+func TestWayPolygonIsCounterclockwise(t *testing.T) {
+	// clockwise: (0,0) (1,0) (1,1) (0,1) in (lat, lon)
+	poly, err := geom.WayPolygon(way(1, 0, 10, 11, 1, 0), grid{})
+	require.NoError(t, err)
+
+	assert.Len(t, poly.Outer, 4)
+	assert.Positive(t, signedArea(poly.Outer))
+	assert.Empty(t, poly.Inner)
+}
+
+func TestWayPolygonErrors(t *testing.T) {
+	test_cases := []struct {
+		name string
+		way  *model.Way
+		want error
+	}{
+		{"open", way(1, 0, 10, 11, 1), geom.ErrNotClosed},
+		{"short", way(1, 0, 10, 0), geom.ErrTooFewNodes},
+		{"bowtie", way(1, 0, 11, 10, 1, 0), geom.ErrInvalidRing},
+		{"unlocated", way(1, 0, 10, 11, 100, 0), geom.ErrMissingLocation},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := geom.WayPolygon(tc.way, grid{})
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
+
+func TestMultipolygonNestsRings(t *testing.T) {
+	// the outer ring, (0,0) to (9,9), is split into two ways, one of them
+	// running backwards
+	south := way(1, 0, 9, 99)
+	north := way(2, 0, 90, 99)
+	hole := way(3, 11, 18, 88, 81, 11)
+	island := way(4, 33, 34, 44, 43, 33)
+
+	// the roles are all "outer", and are ignored
+	mp, err := geom.Multipolygon(multipolygon(5, 3, 1, 4, 2), lookup(south, north, hole, island), grid{})
+	require.NoError(t, err)
+	require.Len(t, mp, 2)
+
+	assert.Len(t, mp[0].Outer, 4)
+	assert.Positive(t, signedArea(mp[0].Outer))
+	require.Len(t, mp[0].Inner, 1)
+	assert.Negative(t, signedArea(mp[0].Inner[0]))
+
+	assert.Len(t, mp[1].Outer, 4)
+	assert.Positive(t, signedArea(mp[1].Outer))
+	assert.Empty(t, mp[1].Inner)
+	assert.True(t, mp[1].Outer.Contains(3.5, 3.5))
+}
+
+func TestMultipolygonReportsBrokenRings(t *testing.T) {
+	south := way(1, 0, 9, 99)
+	north := way(2, 0, 90, 98)
+	square := way(3, 11, 12, 22, 21, 11)
+
+	_, err := geom.Multipolygon(multipolygon(5, 1, 2, 3), lookup(south, north, square), grid{})
+	require.ErrorIs(t, err, geom.ErrNotClosed)
+
+	var gerr *geom.Error
+	require.ErrorAs(t, err, &gerr)
+	assert.Equal(t, model.RELATION, gerr.Type)
+	assert.Equal(t, model.ID(5), gerr.ID)
+	assert.ElementsMatch(t, []model.ID{1, 2}, gerr.Ways)
+	assert.ElementsMatch(t, []model.ID{98, 99}, gerr.Nodes)
+}
+
+func TestMultipolygonErrors(t *testing.T) {
+	square := way(1, 0, 10, 11, 1, 0)
+
+	_, err := geom.Multipolygon(multipolygon(5, 1, 2), lookup(square), grid{})
+	assert.ErrorIs(t, err, geom.ErrMissingWay)
+
+	route := multipolygon(5, 1)
+	route.Tags["type"] = "route"
+
+	_, err = geom.Multipolygon(route, lookup(square), grid{})
+	assert.ErrorIs(t, err, geom.ErrNotMultipolygon)
+	assert.False(t, errors.Is(err, geom.ErrMissingWay))
+}
@@ -0,0 +1,255 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geom
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	"github.com/golang/geo/s2"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// WayLookup returns the way id, and whether it is known.
+type WayLookup func(id model.ID) (*model.Way, bool)
+
+// IsMultipolygon reports whether r is a relation of type multipolygon or
+// boundary, whose way members enclose areas.
+func IsMultipolygon(r *model.Relation) bool {
+	switch r.Tags["type"] {
+	case "multipolygon", "boundary":
+		return true
+	default:
+		return false
+	}
+}
+
+// Multipolygon returns the areas enclosed by the way members of the
+// multipolygon or boundary relation r.  The ways are looked up with ways and
+// located with locs, unless they hold the locations of their nodes.
+//
+// Ways are joined end to end into closed rings.  Whether a ring is outer or
+// inner is decided by how it nests within the other rings, not by its role:
+// rings within an even number of rings are outer rings, and the others are
+// the holes of the smallest ring they are within.  When rings cannot be
+// assembled, an *Error is reported for each of them, joined with
+// errors.Join.
+func Multipolygon(r *model.Relation, ways WayLookup, locs Locator) (model.MultiPolygon, error) {
+	if !IsMultipolygon(r) {
+		return nil, &Error{Type: model.RELATION, ID: r.ID, Err: ErrNotMultipolygon}
+	}
+
+	segments, err := memberSegments(r, ways, locs)
+	if err != nil {
+		return nil, err
+	}
+
+	rings, errs := joinRings(r.ID, segments)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if len(rings) == 0 {
+		return nil, &Error{Type: model.RELATION, ID: r.ID, Err: ErrTooFewNodes}
+	}
+
+	return nest(rings), nil
+}
+
+// segment is a way member of a multipolygon.
+type segment struct {
+	way    model.ID
+	nodes  []model.ID
+	points model.LineString
+}
+
+// ring is a closed ring, joined from one or more segments.
+type ring struct {
+	points model.Ring
+	loop   *s2.Loop
+	area   float64
+	depth  int   // the number of rings this ring is within
+	parent *ring // the smallest ring this ring is within, if any
+}
+
+// memberSegments looks up and locates the way members of r.
+func memberSegments(r *model.Relation, ways WayLookup, locs Locator) ([]segment, error) {
+	var (
+		segments []segment
+		errs     []error
+	)
+
+	for _, m := range r.Members {
+		if m.Type != model.WAY {
+			continue
+		}
+
+		w, ok := ways(m.ID)
+		if !ok {
+			errs = append(errs, &Error{Type: model.RELATION, ID: r.ID, Ways: []model.ID{m.ID}, Err: ErrMissingWay})
+
+			continue
+		}
+
+		if len(w.NodeIDs) < 2 {
+			errs = append(errs, &Error{Type: model.RELATION, ID: r.ID, Ways: []model.ID{w.ID}, Err: ErrTooFewNodes})
+
+			continue
+		}
+
+		points, missing := locate(w, locs)
+		if len(missing) > 0 {
+			errs = append(errs, &Error{
+				Type:  model.RELATION,
+				ID:    r.ID,
+				Ways:  []model.ID{w.ID},
+				Nodes: missing,
+				Err:   ErrMissingLocation,
+			})
+
+			continue
+		}
+
+		segments = append(segments, segment{way: w.ID, nodes: w.NodeIDs, points: points})
+	}
+
+	return segments, errors.Join(errs...)
+}
+
+// joinRings joins segments end to end into closed rings.
+func joinRings(id model.ID, segments []segment) ([]*ring, []error) {
+	// the unused segments that start or end at a node
+	ends := map[model.ID][]int{}
+
+	for i, s := range segments {
+		first, last := s.nodes[0], s.nodes[len(s.nodes)-1]
+		if first != last {
+			ends[first] = append(ends[first], i)
+			ends[last] = append(ends[last], i)
+		}
+	}
+
+	used := make([]bool, len(segments))
+
+	var (
+		rings []*ring
+		errs  []error
+	)
+
+	for i, s := range segments {
+		if used[i] {
+			continue
+		}
+
+		used[i] = true
+
+		ways := []model.ID{s.way}
+		nodes := slices.Clone(s.nodes)
+		points := slices.Clone(s.points)
+
+		// the ring is extended at its end, and then, once turned around,
+		// at its start
+		turned := false
+
+		for nodes[0] != nodes[len(nodes)-1] {
+			end := nodes[len(nodes)-1]
+
+			j := slices.IndexFunc(ends[end], func(j int) bool { return !used[j] })
+			if j < 0 && turned {
+				break
+			} else if j < 0 {
+				ways, nodes, points = reversed(ways), reversed(nodes), reversed(points)
+				turned = true
+
+				continue
+			}
+
+			next := segments[ends[end][j]]
+			used[ends[end][j]] = true
+
+			nextNodes, nextPoints := next.nodes, next.points
+			if nextNodes[0] != end {
+				nextNodes, nextPoints = reversed(nextNodes), reversed(nextPoints)
+			}
+
+			ways = append(ways, next.way)
+			nodes = append(nodes, nextNodes[1:]...)
+			points = append(points, nextPoints[1:]...)
+		}
+
+		if nodes[0] != nodes[len(nodes)-1] {
+			errs = append(errs, &Error{
+				Type:  model.RELATION,
+				ID:    id,
+				Ways:  ways,
+				Nodes: []model.ID{nodes[0], nodes[len(nodes)-1]},
+				Err:   ErrNotClosed,
+			})
+
+			continue
+		}
+
+		closed, loop, err := newRing(points[:len(points)-1])
+		if err != nil {
+			errs = append(errs, &Error{Type: model.RELATION, ID: id, Ways: ways, Err: err})
+
+			continue
+		}
+
+		rings = append(rings, &ring{points: closed, loop: loop, area: loop.Area()})
+	}
+
+	return rings, errs
+}
+
+// nest assigns each ring to the smallest ring it is within, and returns the
+// polygons formed by the outer rings and their holes, largest first.
+func nest(rings []*ring) model.MultiPolygon {
+	slices.SortStableFunc(rings, func(a, b *ring) int { return cmp.Compare(b.area, a.area) })
+
+	for i, r := range rings {
+		// the rings are sorted by descending area, so the last ring
+		// containing r is the smallest
+		for j := i - 1; j >= 0; j-- {
+			if rings[j].loop.Contains(r.loop) {
+				r.parent = rings[j]
+				r.depth = rings[j].depth + 1
+
+				break
+			}
+		}
+	}
+
+	var mp model.MultiPolygon
+
+	outers := map[*ring]int{}
+
+	for _, r := range rings {
+		if r.depth%2 == 0 {
+			outers[r] = len(mp)
+			mp = append(mp, model.Polygon{Outer: r.points})
+
+			continue
+		}
+
+		// holes are clockwise
+		i := outers[r.parent]
+		mp[i].Inner = append(mp[i].Inner, reversed(r.points))
+	}
+
+	return mp
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pbf
+
+import (
+	"context"
+	"io"
+
+	"github.com/destel/rill"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// Handler processes decoded entities, one method per entity type.
+type Handler interface {
+	// Node handles a decoded node.
+	Node(n *model.Node)
+
+	// Way handles a decoded way.
+	Way(w *model.Way)
+
+	// Relation handles a decoded relation.
+	Relation(r *model.Relation)
+}
+
+// HandlerMode selects how Apply invokes handlers.
+type HandlerMode int
+
+const (
+	// ConcurrentHandlers invokes the handlers from several goroutines, each
+	// working through a batch of entities, so that handlers must be safe for
+	// concurrent use.  Batches are handled in no particular order.
+	ConcurrentHandlers HandlerMode = iota
+
+	// SequentialHandlers invokes the handlers from a single goroutine, in
+	// file order.
+	SequentialHandlers
+)
+
+// Apply decodes rdr, with the default options, passing every entity to each
+// of the handlers in turn.  Handlers are invoked concurrently, as for
+// ConcurrentHandlers; use NewDecoder with WithHandlerMode and Decoder.Apply to
+// choose otherwise.
+func Apply(ctx context.Context, rdr io.Reader, handlers ...Handler) error {
+	d, err := NewDecoder(ctx, rdr)
+	if err != nil {
+		return err
+	}
+
+	return d.Apply(handlers...)
+}
+
+// Apply passes every decoded entity to each of the handlers in turn, as
+// selected by WithHandlerMode.  It returns the first decoding error, if any.
+// The decoder is closed once Apply returns.
+func (d *Decoder) Apply(handlers ...Handler) error {
+	defer d.Close()
+
+	return rill.ForEach(d.Entities, d.handlerWorkers, func(batch []model.Entity) error {
+		for _, e := range batch {
+			for _, h := range handlers {
+				switch e := e.(type) {
+				case *model.Node:
+					h.Node(e)
+				case *model.Way:
+					h.Way(e)
+				case *model.Relation:
+					h.Relation(e)
+				}
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pbf
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// countingHandler counts the entities of each type, and may be used
+// concurrently.
+type countingHandler struct {
+	nodes, ways, relations atomic.Int64
+}
+
+func (h *countingHandler) Node(*model.Node)         { h.nodes.Add(1) }
+func (h *countingHandler) Way(*model.Way)           { h.ways.Add(1) }
+func (h *countingHandler) Relation(*model.Relation) { h.relations.Add(1) }
+
+// recordingHandler records the IDs of the entities in the order they are
+// handled.
+type recordingHandler struct {
+	ids []model.ID
+}
+
+func (h *recordingHandler) Node(n *model.Node)         { h.ids = append(h.ids, n.ID) }
+func (h *recordingHandler) Way(w *model.Way)           { h.ids = append(h.ids, w.ID) }
+func (h *recordingHandler) Relation(r *model.Relation) { h.ids = append(h.ids, r.ID) }
+
+func TestApplyPassesEveryEntityToEachHandler(t *testing.T) {
+	in, err := os.Open("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer in.Close()
+
+	first, second := &countingHandler{}, &countingHandler{}
+
+	require.NoError(t, Apply(context.Background(), in, first, second))
+
+	for _, h := range []*countingHandler{first, second} {
+		assert.Equal(t, int64(290), h.nodes.Load())
+		assert.Equal(t, int64(44), h.ways.Load())
+		assert.Equal(t, int64(5), h.relations.Load())
+	}
+}
+
+func TestApplySequentiallyInFileOrder(t *testing.T) {
+	in, err := os.Open("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer in.Close()
+
+	fi, err := in.Stat()
+	require.NoError(t, err)
+
+	ordered, err := NewDecoderAt(context.Background(), in, fi.Size())
+	require.NoError(t, err)
+
+	want := decodeIDs(t, ordered)
+
+	d := openSample(t, WithHandlerMode(SequentialHandlers), WithNCpus(4), WithProtoBatchSize(1))
+
+	h := &recordingHandler{}
+	require.NoError(t, d.Apply(h))
+
+	assert.Equal(t, want, h.ids)
+}
@@ -0,0 +1,316 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pbf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/destel/rill"
+
+	"m4o.io/pbf/v2/internal/decoder"
+	"m4o.io/pbf/v2/model"
+)
+
+const (
+	indexMagic   = "PBFIDX"
+	indexVersion = 1
+)
+
+// ErrInvalidIndex is returned when a persisted index cannot be read.
+var ErrInvalidIndex = errors.New("invalid index")
+
+// Index is a random-access index of the primitive blobs of a PBF file.  It
+// is built once from an io.ReaderAt and can be persisted as a sidecar file
+// so that later readers can jump directly to the blobs that matter.
+type Index struct {
+	// Size is the size, in bytes, of the indexed PBF file.
+	Size int64
+
+	// Blobs holds an entry for each primitive blob, in file order.
+	Blobs []BlobIndex
+}
+
+// BlobIndex describes a single primitive blob of a PBF file.
+type BlobIndex struct {
+	// Offset is the position of the blob within the file.
+	Offset int64
+
+	// Size is the number of bytes the blob, including its header, occupies.
+	Size int64
+
+	// Types holds the entity types found in the blob, in ascending order.
+	Types []model.EntityType
+
+	// MinID and MaxID are the smallest and largest entity IDs in the blob.
+	MinID model.ID
+	MaxID model.ID
+
+	// BoundingBox bounds the nodes of the blob; nil if there are none.
+	BoundingBox *model.BoundingBox
+}
+
+// BuildIndex scans the first size bytes of rdr and returns an index of its
+// primitive blobs.  Blobs are summarized concurrently, using the number of
+// CPUs configured with WithNCpus.
+func BuildIndex(ctx context.Context, rdr io.ReaderAt, size int64, opts ...DecoderOption) (*Index, error) {
+	cfg := defaultDecoderConfig
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	locations := rill.FromSeq2(decoder.ScanBlobs(ctx, rdr, size))
+
+	primitives := rill.Filter(locations, 1, func(loc decoder.BlobLocation) (bool, error) {
+		return loc.Type == decoder.DataType, nil
+	})
+
+	blobs := rill.OrderedMap(primitives, int(cfg.nCPU), func(loc decoder.BlobLocation) (BlobIndex, error) {
+		return indexBlob(rdr, loc)
+	})
+
+	idx := &Index{Size: size}
+
+	err := rill.ForEach(blobs, 1, func(b BlobIndex) error {
+		idx.Blobs = append(idx.Blobs, b)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+// Find returns the blobs that may contain the entity of type t with the
+// given id.
+func (idx *Index) Find(t model.EntityType, id model.ID) []BlobIndex {
+	var found []BlobIndex
+
+	for _, b := range idx.Blobs {
+		if b.MayContain(t, id) {
+			found = append(found, b)
+		}
+	}
+
+	return found
+}
+
+// HasType reports whether the blob holds entities of type t.
+func (b BlobIndex) HasType(t model.EntityType) bool {
+	for _, bt := range b.Types {
+		if bt == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MayContain reports whether the blob may hold the entity of type t with the
+// given id.
+func (b BlobIndex) MayContain(t model.EntityType, id model.ID) bool {
+	return b.HasType(t) && b.MinID <= id && id <= b.MaxID
+}
+
+// Intersects reports whether the nodes of the blob may lie within bbox.
+func (b BlobIndex) Intersects(bbox *model.BoundingBox) bool {
+	if b.BoundingBox == nil {
+		return false
+	}
+
+	return b.BoundingBox.Left <= bbox.Right && bbox.Left <= b.BoundingBox.Right &&
+		b.BoundingBox.Bottom <= bbox.Top && bbox.Bottom <= b.BoundingBox.Top
+}
+
+// ReadBlob decodes the entities of the indexed blob b from rdr.
+func ReadBlob(rdr io.ReaderAt, b BlobIndex) ([]model.Entity, error) {
+	blob, err := decoder.ReadBlobAt(rdr, b.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder.DecodeBlob(blob, decoder.NewFilter(decoder.AllTypes, false, nil))
+}
+
+// WriteTo persists the index to w.  It implements io.WriterTo.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	buf := make([]byte, 0, len(indexMagic)+1+len(idx.Blobs)*(4*binary.MaxVarintLen64))
+
+	buf = append(buf, indexMagic...)
+	buf = append(buf, indexVersion)
+	buf = binary.AppendVarint(buf, idx.Size)
+	buf = binary.AppendUvarint(buf, uint64(len(idx.Blobs)))
+
+	for _, b := range idx.Blobs {
+		buf = binary.AppendVarint(buf, b.Offset)
+		buf = binary.AppendVarint(buf, b.Size)
+
+		var types byte
+		for _, t := range b.Types {
+			types |= 1 << t
+		}
+
+		buf = append(buf, types)
+		buf = binary.AppendVarint(buf, int64(b.MinID))
+		buf = binary.AppendVarint(buf, int64(b.MaxID))
+
+		if b.BoundingBox == nil {
+			buf = append(buf, 0)
+
+			continue
+		}
+
+		buf = append(buf, 1)
+		buf = binary.AppendVarint(buf, b.BoundingBox.Top.Coordinate())
+		buf = binary.AppendVarint(buf, b.BoundingBox.Left.Coordinate())
+		buf = binary.AppendVarint(buf, b.BoundingBox.Bottom.Coordinate())
+		buf = binary.AppendVarint(buf, b.BoundingBox.Right.Coordinate())
+	}
+
+	n, err := w.Write(buf)
+
+	return int64(n), err
+}
+
+// ReadIndex reads an index previously persisted with Index.WriteTo.
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(indexMagic)+1)
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIndex, err)
+	}
+
+	if !bytes.Equal(magic[:len(indexMagic)], []byte(indexMagic)) {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidIndex)
+	}
+
+	if magic[len(indexMagic)] != indexVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidIndex, magic[len(indexMagic)])
+	}
+
+	ir := indexReader{r: br}
+
+	idx := &Index{Size: ir.varint()}
+
+	count := ir.uvarint()
+
+	for i := uint64(0); i < count && ir.err == nil; i++ {
+		b := BlobIndex{
+			Offset: ir.varint(),
+			Size:   ir.varint(),
+		}
+
+		types := ir.byte()
+		for t := model.NODE; t <= model.RELATION; t++ {
+			if types&(1<<t) != 0 {
+				b.Types = append(b.Types, t)
+			}
+		}
+
+		b.MinID = model.ID(ir.varint())
+		b.MaxID = model.ID(ir.varint())
+
+		if ir.byte() != 0 {
+			b.BoundingBox = &model.BoundingBox{
+				Top:    model.ToDegrees(0, 1, ir.varint()),
+				Left:   model.ToDegrees(0, 1, ir.varint()),
+				Bottom: model.ToDegrees(0, 1, ir.varint()),
+				Right:  model.ToDegrees(0, 1, ir.varint()),
+			}
+		}
+
+		idx.Blobs = append(idx.Blobs, b)
+	}
+
+	if ir.err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIndex, ir.err)
+	}
+
+	return idx, nil
+}
+
+// indexBlob reads the blob at loc and summarizes its contents.
+func indexBlob(rdr io.ReaderAt, loc decoder.BlobLocation) (BlobIndex, error) {
+	blob, err := decoder.ReadBlobAt(rdr, loc.Offset)
+	if err != nil {
+		return BlobIndex{}, err
+	}
+
+	sum, err := decoder.SummarizeBlob(blob)
+	if err != nil {
+		return BlobIndex{}, fmt.Errorf("blob at %d: %w", loc.Offset, err)
+	}
+
+	return BlobIndex{
+		Offset:      loc.Offset,
+		Size:        loc.Size,
+		Types:       sum.Types,
+		MinID:       sum.MinID,
+		MaxID:       sum.MaxID,
+		BoundingBox: sum.BoundingBox,
+	}, nil
+}
+
+// indexReader reads the fields of a persisted index, remembering the first
+// error encountered.
+type indexReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (ir *indexReader) varint() int64 {
+	if ir.err != nil {
+		return 0
+	}
+
+	var v int64
+	v, ir.err = binary.ReadVarint(ir.r)
+
+	return v
+}
+
+func (ir *indexReader) uvarint() uint64 {
+	if ir.err != nil {
+		return 0
+	}
+
+	var v uint64
+	v, ir.err = binary.ReadUvarint(ir.r)
+
+	return v
+}
+
+func (ir *indexReader) byte() byte {
+	if ir.err != nil {
+		return 0
+	}
+
+	var v byte
+	v, ir.err = ir.r.ReadByte()
+
+	return v
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pbf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2/model"
+)
+
+func buildSampleIndex(t *testing.T) (*os.File, *Index) {
+	t.Helper()
+
+	in, err := os.Open("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	t.Cleanup(func() { in.Close() })
+
+	fi, err := in.Stat()
+	require.NoError(t, err)
+
+	idx, err := BuildIndex(context.Background(), in, fi.Size())
+	require.NoError(t, err)
+
+	return in, idx
+}
+
+func TestBuildIndexCoversAllEntities(t *testing.T) {
+	in, idx := buildSampleIndex(t)
+
+	require.NotEmpty(t, idx.Blobs)
+
+	var n int
+
+	for _, b := range idx.Blobs {
+		entities, err := ReadBlob(in, b)
+		require.NoError(t, err)
+
+		for _, e := range entities {
+			assert.True(t, b.MayContain(model.TypeOf(e), e.GetID()), "blob %+v missing %d", b, e.GetID())
+
+			if node, ok := e.(*model.Node); ok {
+				assert.True(t, b.BoundingBox.Contains(node.Lat, node.Lon))
+			}
+		}
+
+		n += len(entities)
+	}
+
+	assert.Equal(t, 339, n)
+}
+
+func TestIndexFind(t *testing.T) {
+	in, idx := buildSampleIndex(t)
+
+	last := idx.Blobs[len(idx.Blobs)-1]
+	entities, err := ReadBlob(in, last)
+	require.NoError(t, err)
+
+	target := entities[len(entities)-1]
+	found := idx.Find(model.TypeOf(target), target.GetID())
+
+	assert.Contains(t, found, last)
+}
+
+func TestIndexPersistenceRoundTrip(t *testing.T) {
+	_, idx := buildSampleIndex(t)
+
+	var buf bytes.Buffer
+
+	n, err := idx.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	read, err := ReadIndex(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, idx.Size, read.Size)
+	require.Len(t, read.Blobs, len(idx.Blobs))
+
+	for i, b := range idx.Blobs {
+		r := read.Blobs[i]
+		assert.Equal(t, b.Offset, r.Offset)
+		assert.Equal(t, b.Size, r.Size)
+		assert.Equal(t, b.Types, r.Types)
+		assert.Equal(t, b.MinID, r.MinID)
+		assert.Equal(t, b.MaxID, r.MaxID)
+
+		if b.BoundingBox == nil {
+			assert.Nil(t, r.BoundingBox)
+		} else {
+			assert.True(t, b.BoundingBox.EqualWithin(r.BoundingBox, model.E9))
+		}
+	}
+}
+
+func TestReadIndexRejectsGarbage(t *testing.T) {
+	_, err := ReadIndex(bytes.NewReader([]byte("not an index")))
+
+	if !errors.Is(err, ErrInvalidIndex) {
+		t.Fatalf("expected ErrInvalidIndex, got: %v", err)
+	}
+}
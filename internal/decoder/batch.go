@@ -1,6 +1,7 @@
 package decoder
 
 import (
+	"fmt"
 	"log/slog"
 
 	"github.com/destel/rill"
@@ -10,9 +11,17 @@ import (
 	"m4o.io/pbf/v2/model"
 )
 
+// GenerateBatchDecoder returns a function that decodes batches of primitive
+// blobs, keeping the entities selected by f.
+func GenerateBatchDecoder(f *Filter) func(array []*pb.Blob) <-chan rill.Try[[]model.Entity] {
+	return func(array []*pb.Blob) <-chan rill.Try[[]model.Entity] {
+		return DecodeBatch(array, f)
+	}
+}
+
 // DecodeBatch unpacks a batch of primitive blobs and parses them into
 // primitive blocks which are subsequently sent down the out channel.
-func DecodeBatch(array []*pb.Blob) (out <-chan rill.Try[[]model.Entity]) {
+func DecodeBatch(array []*pb.Blob, f *Filter) (out <-chan rill.Try[[]model.Entity]) {
 	ch := make(chan rill.Try[[]model.Entity])
 	out = ch
 
@@ -25,17 +34,9 @@ func DecodeBatch(array []*pb.Blob) (out <-chan rill.Try[[]model.Entity]) {
 		for _, blob := range array {
 			buf.Reset()
 
-			unpacked, err := unpack(buf, blob)
-			if err != nil {
-				slog.Error("unable to unpack blob", "error", err)
-				ch <- rill.Try[[]model.Entity]{Error: err}
-
-				return
-			}
-
-			entities, err := parsePrimitiveBlock(unpacked)
+			entities, err := decodeBlob(buf, blob, f)
 			if err != nil {
-				slog.Error("unable to parse block", "error", err)
+				slog.Error("unable to decode blob", "error", err)
 				ch <- rill.Try[[]model.Entity]{Error: err}
 
 				return
@@ -47,3 +48,27 @@ func DecodeBatch(array []*pb.Blob) (out <-chan rill.Try[[]model.Entity]) {
 
 	return out
 }
+
+// DecodeBlob unpacks a single primitive blob and parses it into the entities
+// selected by f.
+func DecodeBlob(blob *pb.Blob, f *Filter) ([]model.Entity, error) {
+	buf := core.NewPooledBuffer()
+	defer buf.Close()
+
+	return decodeBlob(buf, blob, f)
+}
+
+// decodeBlob unpacks blob into buf and parses the primitive block it holds.
+func decodeBlob(buf *core.PooledBuffer, blob *pb.Blob, f *Filter) ([]model.Entity, error) {
+	unpacked, err := unpack(buf, blob)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unpack blob: %w", err)
+	}
+
+	entities, err := parsePrimitiveBlock(unpacked, f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse block: %w", err)
+	}
+
+	return entities, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoder
+
+import (
+	"sync/atomic"
+
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/tagfilter"
+)
+
+// TypeSet is a set of entity types.
+type TypeSet uint8
+
+// AllTypes is the set of all entity types.
+const AllTypes = TypeSet(1<<model.NODE | 1<<model.WAY | 1<<model.RELATION)
+
+// NewTypeSet returns the set of the given entity types.
+func NewTypeSet(types ...model.EntityType) TypeSet {
+	var s TypeSet
+
+	for _, t := range types {
+		s |= 1 << t
+	}
+
+	return s
+}
+
+// Has reports whether t is a member of the set.
+func (s TypeSet) Has(t model.EntityType) bool {
+	return s&(1<<t) != 0
+}
+
+// Filter selects which primitive groups, and which of their entities, are
+// decoded.  A Filter is shared by all the workers of a decoding pipeline.
+type Filter struct {
+	types  TypeSet
+	sorted bool
+	tags   *tagfilter.Expr
+
+	// exhausted is set once a sorted input has moved past every wanted type.
+	exhausted atomic.Bool
+}
+
+// NewFilter returns a filter that keeps entities of the given types.  When
+// sorted is set, the input is known to be ordered by type then ID, allowing
+// the remainder of the input to be skipped once every wanted type has been
+// read.  When tags is not nil, only the entities whose tags match it are
+// kept.
+func NewFilter(types TypeSet, sorted bool, tags *tagfilter.Expr) *Filter {
+	return &Filter{types: types, sorted: sorted, tags: tags}
+}
+
+// Types returns the entity types kept by the filter.
+func (f *Filter) Types() TypeSet {
+	return f.types
+}
+
+// Exhausted reports whether the remainder of a sorted input is known to hold
+// no wanted entities.
+func (f *Filter) Exhausted() bool {
+	return f.exhausted.Load()
+}
+
+// wants reports whether entities of type t are to be decoded, noting when a
+// sorted input has moved beyond the wanted types.
+func (f *Filter) wants(t model.EntityType) bool {
+	if f.types.Has(t) {
+		return true
+	}
+
+	if f.sorted && f.types&^(1<<(t+1)-1) == 0 {
+		f.exhausted.Store(true)
+	}
+
+	return false
+}
+
+// Until wraps seq so that iteration ends once f is exhausted.  Items yielded
+// before then are still decoded, their groups filtered as usual.
+func Until[T any](f *Filter, seq func(yield func(T, error) bool)) func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		for v, err := range seq {
+			if f.Exhausted() || !yield(v, err) {
+				return
+			}
+		}
+	}
+}
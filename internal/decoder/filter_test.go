@@ -0,0 +1,84 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"m4o.io/pbf/v2/model"
+)
+
+func TestTypeSet(t *testing.T) {
+	s := NewTypeSet(model.NODE, model.RELATION)
+
+	assert.True(t, s.Has(model.NODE))
+	assert.False(t, s.Has(model.WAY))
+	assert.True(t, s.Has(model.RELATION))
+
+	for _, et := range []model.EntityType{model.NODE, model.WAY, model.RELATION} {
+		assert.True(t, AllTypes.Has(et))
+	}
+}
+
+func TestFilterExhaustsSortedInput(t *testing.T) {
+	f := NewFilter(NewTypeSet(model.NODE), true, nil)
+
+	assert.True(t, f.wants(model.NODE))
+	assert.False(t, f.Exhausted())
+
+	assert.False(t, f.wants(model.WAY))
+	assert.True(t, f.Exhausted())
+}
+
+func TestFilterDoesNotExhaustBeforeWantedTypes(t *testing.T) {
+	f := NewFilter(NewTypeSet(model.RELATION), true, nil)
+
+	assert.False(t, f.wants(model.NODE))
+	assert.False(t, f.wants(model.WAY))
+	assert.False(t, f.Exhausted())
+	assert.True(t, f.wants(model.RELATION))
+}
+
+func TestFilterDoesNotExhaustUnsortedInput(t *testing.T) {
+	f := NewFilter(NewTypeSet(model.NODE), false, nil)
+
+	assert.False(t, f.wants(model.WAY))
+	assert.False(t, f.Exhausted())
+}
+
+func TestUntilStopsOnceExhausted(t *testing.T) {
+	f := NewFilter(NewTypeSet(model.NODE), true, nil)
+
+	seq := func(yield func(int, error) bool) {
+		for i := range 5 {
+			if !yield(i, nil) {
+				return
+			}
+		}
+	}
+
+	var got []int
+
+	for v := range Until(f, seq) {
+		got = append(got, v)
+		if v == 1 {
+			f.wants(model.WAY)
+		}
+	}
+
+	assert.Equal(t, []int{0, 1}, got)
+}
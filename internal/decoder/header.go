@@ -63,8 +63,9 @@ func LoadHeader(reader io.Reader) (model.Header, error) {
 		}
 	}
 
-	if hb.OsmosisReplicationTimestamp != nil {
-		hdr.OsmosisReplicationTimestamp = time.Unix(*hb.OsmosisReplicationTimestamp, 0)
+	// a timestamp of 0 means that there is none, as for entities
+	if ts := hb.GetOsmosisReplicationTimestamp(); ts != 0 {
+		hdr.OsmosisReplicationTimestamp = time.Unix(ts, 0)
 	}
 
 	return hdr, nil
@@ -22,18 +22,25 @@ import (
 
 	"m4o.io/pbf/v2/internal/pb"
 	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/tagfilter"
 )
 
-func parsePrimitiveBlock(buf []byte) ([]model.Entity, error) {
+func parsePrimitiveBlock(buf []byte, f *Filter) ([]model.Entity, error) {
 	blk := &pb.PrimitiveBlock{}
 	if err := proto.Unmarshal(buf, blk); err != nil {
 		return nil, fmt.Errorf("unable to unmarshal primitive block: %w", err)
 	}
 
 	c := newBlockContext(blk)
+	c.tags = f.tags
 
 	entities := make([]model.Entity, 0)
 	for _, pg := range blk.GetPrimitivegroup() {
+		// A primitive group only ever holds entities of a single type.
+		if t, ok := groupType(pg); !ok || !f.wants(t) {
+			continue
+		}
+
 		entities = append(entities, c.decodeNodes(pg.GetNodes())...)
 		entities = append(entities, c.decodeDenseNodes(pg.GetDense())...)
 		entities = append(entities, c.decodeWays(pg.GetWays())...)
@@ -43,12 +50,31 @@ func parsePrimitiveBlock(buf []byte) ([]model.Entity, error) {
 	return entities, nil
 }
 
+// groupType returns the type of the entities held by the primitive group, or
+// false if the group is empty.
+func groupType(pg *pb.PrimitiveGroup) (model.EntityType, bool) {
+	switch {
+	case len(pg.GetNodes()) > 0, len(pg.GetDense().GetId()) > 0:
+		return model.NODE, true
+	case len(pg.GetWays()) > 0:
+		return model.WAY, true
+	case len(pg.GetRelations()) > 0:
+		return model.RELATION, true
+	default:
+		return 0, false
+	}
+}
+
 type blockContext struct {
 	strings         []string
 	granularity     int32
 	latOffset       int64
 	lonOffset       int64
 	dateGranularity int32
+
+	// tags, when not nil, is the filter the tags of decoded entities must
+	// match.
+	tags *tagfilter.Expr
 }
 
 func newBlockContext(pb *pb.PrimitiveBlock) *blockContext {
@@ -62,16 +88,20 @@ func newBlockContext(pb *pb.PrimitiveBlock) *blockContext {
 }
 
 func (c *blockContext) decodeNodes(nodes []*pb.Node) (entities []model.Entity) {
-	entities = make([]model.Entity, len(nodes))
+	entities = make([]model.Entity, 0, len(nodes))
 
-	for i, node := range nodes {
-		entities[i] = &model.Node{
+	for _, node := range nodes {
+		if !c.matches(node.GetKeys(), node.GetVals()) {
+			continue
+		}
+
+		entities = append(entities, &model.Node{
 			ID:   model.ID(node.GetId()),
 			Tags: c.decodeTags(node.GetKeys(), node.GetVals()),
 			Info: c.decodeInfo(node.GetInfo()),
 			Lat:  model.ToDegrees(c.latOffset, c.granularity, node.GetLat()),
 			Lon:  model.ToDegrees(c.lonOffset, c.granularity, node.GetLon()),
-		}
+		})
 	}
 
 	return entities
@@ -79,7 +109,7 @@ func (c *blockContext) decodeNodes(nodes []*pb.Node) (entities []model.Entity) {
 
 func (c *blockContext) decodeDenseNodes(nodes *pb.DenseNodes) []model.Entity {
 	ids := nodes.GetId()
-	entities := make([]model.Entity, len(ids))
+	entities := make([]model.Entity, 0, len(ids))
 
 	tic := c.newTagsContext(nodes.GetKeysVals())
 	dic := c.newDenseInfoContext(nodes.GetDenseinfo())
@@ -92,22 +122,35 @@ func (c *blockContext) decodeDenseNodes(nodes *pb.DenseNodes) []model.Entity {
 		lat += lats[i]
 		lon += lons[i]
 
-		entities[i] = &model.Node{
+		// the delta coded info has to be accumulated for every node, even
+		// those that are filtered out
+		dic.next(i)
+
+		keyVals := tic.next()
+		if !c.matchesKeyVals(keyVals) {
+			continue
+		}
+
+		entities = append(entities, &model.Node{
 			ID:   model.ID(id),
-			Tags: tic.decodeTags(),
+			Tags: tic.decodeTags(keyVals),
 			Info: dic.decodeInfo(i),
 			Lat:  model.ToDegrees(c.latOffset, c.granularity, lat),
 			Lon:  model.ToDegrees(c.lonOffset, c.granularity, lon),
-		}
+		})
 	}
 
 	return entities
 }
 
 func (c *blockContext) decodeWays(nodes []*pb.Way) []model.Entity {
-	entities := make([]model.Entity, len(nodes))
+	entities := make([]model.Entity, 0, len(nodes))
+
+	for _, node := range nodes {
+		if !c.matches(node.GetKeys(), node.GetVals()) {
+			continue
+		}
 
-	for i, node := range nodes {
 		refs := node.GetRefs()
 		nodeIDs := make([]model.ID, len(refs))
 
@@ -118,27 +161,58 @@ func (c *blockContext) decodeWays(nodes []*pb.Way) []model.Entity {
 			nodeIDs[j] = model.ID(nodeID)
 		}
 
-		entities[i] = &model.Way{
+		lats, lons := c.decodeLocations(node.GetLat(), node.GetLon(), len(refs))
+
+		entities = append(entities, &model.Way{
 			ID:      model.ID(node.GetId()),
 			Tags:    c.decodeTags(node.GetKeys(), node.GetVals()),
 			NodeIDs: nodeIDs,
 			Info:    c.decodeInfo(node.GetInfo()),
-		}
+			Lats:    lats,
+			Lons:    lons,
+		})
 	}
 
 	return entities
 }
 
+// decodeLocations decodes the delta coded coordinates of the n nodes of a
+// way.  Nil slices are returned unless there is a coordinate pair for every
+// node.
+func (c *blockContext) decodeLocations(latDeltas, lonDeltas []int64, n int) ([]model.Degrees, []model.Degrees) {
+	if n == 0 || len(latDeltas) != n || len(lonDeltas) != n {
+		return nil, nil
+	}
+
+	lats := make([]model.Degrees, n)
+	lons := make([]model.Degrees, n)
+
+	var lat, lon int64
+
+	for i := range n {
+		lat += latDeltas[i]
+		lon += lonDeltas[i]
+		lats[i] = model.ToDegrees(c.latOffset, c.granularity, lat)
+		lons[i] = model.ToDegrees(c.lonOffset, c.granularity, lon)
+	}
+
+	return lats, lons
+}
+
 func (c *blockContext) decodeRelations(nodes []*pb.Relation) []model.Entity {
-	entities := make([]model.Entity, len(nodes))
+	entities := make([]model.Entity, 0, len(nodes))
 
-	for i, node := range nodes {
-		entities[i] = &model.Relation{
+	for _, node := range nodes {
+		if !c.matches(node.GetKeys(), node.GetVals()) {
+			continue
+		}
+
+		entities = append(entities, &model.Relation{
 			ID:      model.ID(node.GetId()),
 			Tags:    c.decodeTags(node.GetKeys(), node.GetVals()),
 			Info:    c.decodeInfo(node.GetInfo()),
 			Members: c.decodeMembers(node),
-		}
+		})
 	}
 
 	return entities
@@ -164,6 +238,43 @@ func (c *blockContext) decodeMembers(node *pb.Relation) []model.Member {
 	return members
 }
 
+// matches reports whether the tags, given as indexes into the string table,
+// satisfy the tag filter.  The tags are looked up in place, so that no map is
+// built for the entities that are filtered out.
+func (c *blockContext) matches(keyIDs, valIDs []uint32) bool {
+	if c.tags == nil {
+		return true
+	}
+
+	return c.tags.MatchLookup(func(key string) (string, bool) {
+		for i, keyID := range keyIDs {
+			if c.strings[keyID] == key {
+				return c.strings[valIDs[i]], true
+			}
+		}
+
+		return "", false
+	})
+}
+
+// matchesKeyVals is matches for the interleaved key and value indexes of a
+// dense node.
+func (c *blockContext) matchesKeyVals(keyVals []int32) bool {
+	if c.tags == nil {
+		return true
+	}
+
+	return c.tags.MatchLookup(func(key string) (string, bool) {
+		for i := 0; i < len(keyVals); i += 2 {
+			if c.strings[keyVals[i]] == key {
+				return c.strings[keyVals[i+1]], true
+			}
+		}
+
+		return "", false
+	})
+}
+
 func (c *blockContext) decodeTags(keyIDs, valIDs []uint32) map[string]string {
 	tags := make(map[string]string, len(keyIDs))
 
@@ -178,7 +289,7 @@ func (c *blockContext) decodeInfo(info *pb.Info) *model.Info {
 	i := &model.Info{Visible: true}
 	if info != nil {
 		i.Version = info.GetVersion()
-		i.Timestamp = toTimestamp(c.dateGranularity, info.GetTimestamp())
+		i.Timestamp = toTimestamp(c.dateGranularity, int64(info.GetTimestamp()))
 		i.Changeset = info.GetChangeset()
 		i.UID = model.UID(info.GetUid())
 
@@ -235,17 +346,22 @@ type denseInfoContext struct {
 	visibilities    []bool
 }
 
-func (dic *denseInfoContext) decodeInfo(i int) *model.Info {
+// next accumulates the deltas of the info of the i-th node.
+func (dic *denseInfoContext) next(i int) {
 	dic.version += dic.versions[i]
 	dic.uid += dic.uids[i]
 	dic.timestamp += dic.timestamps[i]
 	dic.changeset += dic.changesets[i]
 	dic.userSid += dic.userSids[i]
+}
 
+// decodeInfo returns the info of the i-th node, once next has accumulated its
+// deltas.
+func (dic *denseInfoContext) decodeInfo(i int) *model.Info {
 	info := &model.Info{
 		Version:   dic.version,
 		UID:       dic.uid,
-		Timestamp: toTimestamp(dic.dateGranularity, int32(dic.timestamp)),
+		Timestamp: toTimestamp(dic.dateGranularity, dic.timestamp),
 		Changeset: dic.changeset,
 		User:      dic.strings[dic.userSid],
 	}
@@ -275,21 +391,32 @@ func (c *blockContext) newTagsContext(keyVals []int32) *tagsContext {
 	return tc
 }
 
-func (tic *tagsContext) decodeTags() map[string]string {
+// next returns the interleaved key and value indexes of the next node's
+// tags.
+func (tic *tagsContext) next() []int32 {
 	if tic.keyVals == nil {
-		return map[string]string{}
+		return nil
 	}
 
-	tags := make(map[string]string)
 	i := tic.i
 
 	for tic.keyVals[i] > 0 {
-		tags[tic.strings[tic.keyVals[i]]] = tic.strings[tic.keyVals[i+1]]
 		i += 2
 	}
 
+	keyVals := tic.keyVals[tic.i:i]
 	tic.i = i + 1
 
+	return keyVals
+}
+
+func (tic *tagsContext) decodeTags(keyVals []int32) map[string]string {
+	tags := make(map[string]string, len(keyVals)/2)
+
+	for i := 0; i < len(keyVals); i += 2 {
+		tags[tic.strings[keyVals[i]]] = tic.strings[keyVals[i+1]]
+	}
+
 	return tags
 }
 
@@ -308,7 +435,12 @@ func decodeMemberType(mt pb.Relation_MemberType) model.EntityType {
 }
 
 // toTimestamp converts a timestamp with a specific granularity, in units of
-// milliseconds, to a UTC timestamp of type Time.
-func toTimestamp(granularity int32, timestamp int32) time.Time {
-	return time.UnixMilli(int64(timestamp) * int64(granularity)).UTC()
+// milliseconds, to a UTC timestamp of type Time.  A timestamp of 0 means that
+// there is none, and is converted to the zero Time.
+func toTimestamp(granularity int32, timestamp int64) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(timestamp * int64(granularity)).UTC()
 }
@@ -0,0 +1,92 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"m4o.io/pbf/v2/internal/pb"
+)
+
+func TestToTimestamp(t *testing.T) {
+	assert.True(t, toTimestamp(1000, 0).IsZero())
+	assert.Equal(t, time.Date(2022, 2, 13, 20, 40, 22, 0, time.UTC), toTimestamp(1000, 1644784822))
+
+	// dense timestamps are int64s, which can hold milliseconds
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 1e6, time.UTC), toTimestamp(1, 1714521600001))
+}
+
+func TestDecodeZeroTimestamps(t *testing.T) {
+	c := newBlockContext(&pb.PrimitiveBlock{
+		Stringtable:     &pb.StringTable{S: []string{""}},
+		DateGranularity: proto.Int32(1000),
+	})
+
+	nodes := c.decodeDenseNodes(&pb.DenseNodes{
+		Id: []int64{1, 1}, Lat: []int64{0, 0}, Lon: []int64{0, 0},
+		Denseinfo: &pb.DenseInfo{
+			Version: []int32{1, 0}, Timestamp: []int64{0, 1714521600}, Changeset: []int64{0, 0},
+			Uid: []int32{0, 0}, UserSid: []int32{0, 0},
+		},
+	})
+	require.Len(t, nodes, 2)
+	assert.True(t, nodes[0].GetInfo().Timestamp.IsZero())
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), nodes[1].GetInfo().Timestamp)
+
+	ways := c.decodeWays([]*pb.Way{
+		{Id: proto.Int64(1), Info: &pb.Info{Version: proto.Int32(1), Timestamp: proto.Int32(0)}},
+		{Id: proto.Int64(2), Info: &pb.Info{Version: proto.Int32(1), Timestamp: proto.Int32(1714521600)}},
+	})
+	require.Len(t, ways, 2)
+	assert.True(t, ways[0].GetInfo().Timestamp.IsZero())
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), ways[1].GetInfo().Timestamp)
+}
+
+func TestLoadHeaderZeroTimestamp(t *testing.T) {
+	header := func(t *testing.T, timestamp int64) *bytes.Reader {
+		t.Helper()
+
+		data, err := proto.Marshal(&pb.HeaderBlock{OsmosisReplicationTimestamp: proto.Int64(timestamp)})
+		require.NoError(t, err)
+
+		blob, err := proto.Marshal(&pb.Blob{Data: &pb.Blob_Raw{Raw: data}})
+		require.NoError(t, err)
+
+		bh, err := proto.Marshal(&pb.BlobHeader{Type: proto.String("OSMHeader"), Datasize: proto.Int32(int32(len(blob)))})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, binary.Write(&buf, binary.BigEndian, uint32(len(bh))))
+		buf.Write(bh)
+		buf.Write(blob)
+
+		return bytes.NewReader(buf.Bytes())
+	}
+
+	hdr, err := LoadHeader(header(t, 0))
+	require.NoError(t, err)
+	assert.True(t, hdr.OsmosisReplicationTimestamp.IsZero())
+
+	hdr, err = LoadHeader(header(t, 1714521600))
+	require.NoError(t, err)
+	assert.True(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Equal(hdr.OsmosisReplicationTimestamp))
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"m4o.io/pbf/v2/internal/pb"
+)
+
+const (
+	// HeaderType is the blob header type of the OSM header blob.
+	HeaderType = "OSMHeader"
+
+	// DataType is the blob header type of primitive blobs.
+	DataType = "OSMData"
+
+	// maxBlobSize bounds the length prefix, the blob header (64 KiB) and the
+	// blob data (32 MiB) of a single blob, as set out by the PBF specification.
+	maxBlobSize = 4 + 64*1024 + 32*1024*1024
+)
+
+// BlobLocation describes where a blob lives within a PBF file.
+type BlobLocation struct {
+	// Type is the blob header type, e.g. OSMHeader or OSMData.
+	Type string
+
+	// Offset is the position of the blob header's length prefix.
+	Offset int64
+
+	// Size is the combined length of the length prefix, blob header and
+	// blob data.
+	Size int64
+}
+
+// ScanBlobs creates an iterator that returns the location of every blob in
+// the first size bytes of reader.  Only blob headers are read; blob data is
+// skipped over.  Cancelling ctx ends the iteration with the context's error.
+func ScanBlobs(ctx context.Context, reader io.ReaderAt, size int64) func(yield func(loc BlobLocation, err error) bool) {
+	return func(yield func(loc BlobLocation, err error) bool) {
+		var offset int64
+
+		for offset < size {
+			select {
+			case <-ctx.Done():
+				yield(BlobLocation{}, ctx.Err())
+
+				return
+			default:
+			}
+
+			loc, err := scanBlob(reader, offset, size)
+			if err != nil {
+				slog.Error("unable to scan blob", "offset", offset, "error", err)
+				yield(BlobLocation{}, err)
+
+				return
+			}
+
+			if !yield(loc, nil) {
+				return
+			}
+
+			offset += loc.Size
+		}
+	}
+}
+
+// ReadBlobAt reads the blob whose length prefix starts at offset.
+func ReadBlobAt(reader io.ReaderAt, offset int64) (*pb.Blob, error) {
+	return readBlob(io.NewSectionReader(reader, offset, maxBlobSize))
+}
+
+// scanBlob reads the blob header at offset and returns its location.
+func scanBlob(reader io.ReaderAt, offset, size int64) (BlobLocation, error) {
+	sr := io.NewSectionReader(reader, offset, size-offset)
+
+	h, err := readBlobHeader(sr)
+	if err != nil {
+		return BlobLocation{}, fmt.Errorf("error reading blob header: %w", err)
+	}
+
+	hdrSize, err := sr.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return BlobLocation{}, fmt.Errorf("error locating blob data: %w", err)
+	}
+
+	loc := BlobLocation{
+		Type:   h.GetType(),
+		Offset: offset,
+		Size:   hdrSize + int64(h.GetDatasize()),
+	}
+
+	if offset+loc.Size > size {
+		return BlobLocation{}, fmt.Errorf("blob at %d: %w", offset, io.ErrUnexpectedEOF)
+	}
+
+	return loc, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/proto"
+
+	"m4o.io/pbf/v2/internal/core"
+	"m4o.io/pbf/v2/internal/pb"
+	"m4o.io/pbf/v2/model"
+)
+
+// ErrMalformedDenseNodes is returned when the IDs, latitudes and longitudes
+// of dense nodes differ in number.
+var ErrMalformedDenseNodes = errors.New("malformed dense nodes")
+
+// BlobSummary describes the contents of a primitive blob.
+type BlobSummary struct {
+	// Types holds the entity types found in the blob.
+	Types []model.EntityType
+
+	// MinID and MaxID are the smallest and largest entity IDs in the blob.
+	MinID model.ID
+	MaxID model.ID
+
+	// BoundingBox bounds the nodes of the blob; nil if there are none.
+	BoundingBox *model.BoundingBox
+}
+
+// SummarizeBlob unpacks a primitive blob and summarizes its contents without
+// decoding its entities.
+func SummarizeBlob(blob *pb.Blob) (BlobSummary, error) {
+	buf := core.NewPooledBuffer()
+	defer buf.Close()
+
+	unpacked, err := unpack(buf, blob)
+	if err != nil {
+		return BlobSummary{}, fmt.Errorf("error unpacking blob: %w", err)
+	}
+
+	blk := &pb.PrimitiveBlock{}
+	if err := proto.Unmarshal(unpacked, blk); err != nil {
+		return BlobSummary{}, fmt.Errorf("unable to unmarshal primitive block: %w", err)
+	}
+
+	s := &summarizer{
+		c:     newBlockContext(blk),
+		minID: math.MaxInt64,
+		maxID: math.MinInt64,
+	}
+
+	for _, pg := range blk.GetPrimitivegroup() {
+		s.summarizeNodes(pg.GetNodes())
+
+		if err := s.summarizeDenseNodes(pg.GetDense()); err != nil {
+			return BlobSummary{}, err
+		}
+
+		s.summarizeWays(pg.GetWays())
+		s.summarizeRelations(pg.GetRelations())
+	}
+
+	return s.summary(), nil
+}
+
+type summarizer struct {
+	c     *blockContext
+	types [model.RELATION + 1]bool
+	minID int64
+	maxID int64
+	bbox  *model.BoundingBox
+}
+
+func (s *summarizer) summarizeNodes(nodes []*pb.Node) {
+	for _, n := range nodes {
+		s.addID(model.NODE, n.GetId())
+		s.addLatLon(n.GetLat(), n.GetLon())
+	}
+}
+
+func (s *summarizer) summarizeDenseNodes(nodes *pb.DenseNodes) error {
+	ids := nodes.GetId()
+	lats := nodes.GetLat()
+	lons := nodes.GetLon()
+
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return fmt.Errorf("%w: %d ids, %d lats and %d lons", ErrMalformedDenseNodes, len(ids), len(lats), len(lons))
+	}
+
+	var id, lat, lon int64
+	for i := range ids {
+		id += ids[i]
+		lat += lats[i]
+		lon += lons[i]
+
+		s.addID(model.NODE, id)
+		s.addLatLon(lat, lon)
+	}
+
+	return nil
+}
+
+func (s *summarizer) summarizeWays(ways []*pb.Way) {
+	for _, w := range ways {
+		s.addID(model.WAY, w.GetId())
+	}
+}
+
+func (s *summarizer) summarizeRelations(relations []*pb.Relation) {
+	for _, r := range relations {
+		s.addID(model.RELATION, r.GetId())
+	}
+}
+
+func (s *summarizer) addID(t model.EntityType, id int64) {
+	s.types[t] = true
+	s.minID = min(s.minID, id)
+	s.maxID = max(s.maxID, id)
+}
+
+func (s *summarizer) addLatLon(lat, lon int64) {
+	if s.bbox == nil {
+		s.bbox = model.InitialBoundingBox()
+	}
+
+	s.bbox.ExpandWithLatLng(
+		model.ToDegrees(s.c.latOffset, s.c.granularity, lat),
+		model.ToDegrees(s.c.lonOffset, s.c.granularity, lon))
+}
+
+func (s *summarizer) summary() BlobSummary {
+	var sum BlobSummary
+
+	for t, ok := range s.types {
+		if ok {
+			sum.Types = append(sum.Types, model.EntityType(t))
+		}
+	}
+
+	if len(sum.Types) > 0 {
+		sum.MinID = model.ID(s.minID)
+		sum.MaxID = model.ID(s.maxID)
+	}
+
+	sum.BoundingBox = s.bbox
+
+	return sum
+}
@@ -0,0 +1,62 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"m4o.io/pbf/v2/internal/pb"
+)
+
+func rawBlob(t *testing.T, blk *pb.PrimitiveBlock) *pb.Blob {
+	t.Helper()
+
+	raw, err := proto.Marshal(blk)
+	require.NoError(t, err)
+
+	return &pb.Blob{Data: &pb.Blob_Raw{Raw: raw}}
+}
+
+func TestSummarizeBlob(t *testing.T) {
+	blob := rawBlob(t, &pb.PrimitiveBlock{
+		Stringtable: &pb.StringTable{S: []string{""}},
+		Primitivegroup: []*pb.PrimitiveGroup{{
+			Dense: &pb.DenseNodes{Id: []int64{2, 3}, Lat: []int64{10, 5}, Lon: []int64{20, -5}},
+		}},
+	})
+
+	sum, err := SummarizeBlob(blob)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 2, sum.MinID)
+	assert.EqualValues(t, 5, sum.MaxID)
+	assert.NotNil(t, sum.BoundingBox)
+}
+
+func TestSummarizeBlobRejectsMalformedDenseNodes(t *testing.T) {
+	blob := rawBlob(t, &pb.PrimitiveBlock{
+		Stringtable: &pb.StringTable{S: []string{""}},
+		Primitivegroup: []*pb.PrimitiveGroup{{
+			Dense: &pb.DenseNodes{Id: []int64{1, 1, 1}, Lat: []int64{1, 1}, Lon: []int64{1, 1, 1}},
+		}},
+	})
+
+	_, err := SummarizeBlob(blob)
+	assert.ErrorIs(t, err, ErrMalformedDenseNodes)
+}
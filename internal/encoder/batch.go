@@ -2,6 +2,7 @@ package encoder
 
 import (
 	"io"
+	"iter"
 
 	"github.com/destel/rill"
 
@@ -36,6 +37,55 @@ func Coalesce(in <-chan []model.Entity, size int) <-chan rill.Try[[]model.Entity
 	return rill.Merge(bn, br, bw)
 }
 
+// CoalesceSorted batches consecutive entities of in, which are usually ordered
+// by type, into batches of at most size entities of a single type.  Unlike
+// Coalesce, the order of the entities is preserved.
+func CoalesceSorted(in <-chan []model.Entity, size int) <-chan rill.Try[[]model.Entity] {
+	out := make(chan rill.Try[[]model.Entity])
+
+	go func() {
+		defer close(out)
+
+		batch := make([]model.Entity, 0, size)
+
+		for entities := range in {
+			for _, e := range entities {
+				if len(batch) == size || (len(batch) > 0 && model.TypeOf(batch[0]) != model.TypeOf(e)) {
+					out <- rill.Wrap(batch, nil)
+					batch = make([]model.Entity, 0, size)
+				}
+
+				batch = append(batch, e)
+			}
+		}
+
+		if len(batch) > 0 {
+			out <- rill.Wrap(batch, nil)
+		}
+	}()
+
+	return out
+}
+
+// SplitSorted splits entities, which are ordered by type, into batches of at
+// most size entities of a single type.
+func SplitSorted(entities []model.Entity, size int) iter.Seq[[]model.Entity] {
+	return func(yield func([]model.Entity) bool) {
+		for len(entities) > 0 {
+			n := 1
+			for n < len(entities) && n < size && model.TypeOf(entities[n]) == model.TypeOf(entities[0]) {
+				n++
+			}
+
+			if !yield(entities[:n]) {
+				return
+			}
+
+			entities = entities[n:]
+		}
+	}
+}
+
 func ExtractBoundingBoxes(
 	in <-chan rill.Try[[]model.Entity],
 ) (
@@ -77,8 +127,13 @@ func batchEntities[T model.Entity](in <-chan rill.Try[model.Entity], size int) <
 	return rill.Batch(nodes, size, -1)
 }
 
-func EncodeBatch(batch []model.Entity) (*pb.PrimitiveBlock, error) {
-	return newBlockContext(batch).extractPrimitiveBlock(), nil
+// GenerateBatchEncoder creates a function that encodes a batch of entities,
+// all of the same type, into a primitive block with the precision p.  The
+// coordinates of the nodes of ways are written when locationsOnWays is set.
+func GenerateBatchEncoder(p Precision, locationsOnWays bool) func(batch []model.Entity) (*pb.PrimitiveBlock, error) {
+	return func(batch []model.Entity) (*pb.PrimitiveBlock, error) {
+		return newBlockContext(batch, p, locationsOnWays).extractPrimitiveBlock(), nil
+	}
 }
 
 func SavePacked(w io.Writer, ch <-chan rill.Try[[]byte]) <-chan rill.Try[struct{}] {
@@ -95,8 +150,8 @@ func SavePacked(w io.Writer, ch <-chan rill.Try[[]byte]) <-chan rill.Try[struct{
 	return out
 }
 
-func GenerateBatchPacker(c BlobCompression) func(block *pb.PrimitiveBlock) ([]byte, error) {
+func GenerateBatchPacker(c BlobCompression, level int) func(block *pb.PrimitiveBlock) ([]byte, error) {
 	return func(block *pb.PrimitiveBlock) ([]byte, error) {
-		return Pack(block, c)
+		return Pack(block, c, level)
 	}
 }
@@ -26,8 +26,8 @@ import (
 
 // writeBlob marshals a Protobuf Message, msg, into a PBF blob and writes its
 // blob header and blob data to the wrtr.
-func writeBlob(wrtr io.Writer, msg proto.Message, c BlobCompression) (err error) {
-	bb, err := Pack(msg, c)
+func writeBlob(wrtr io.Writer, msg proto.Message, c BlobCompression, level int) (err error) {
+	bb, err := Pack(msg, c, level)
 	if err != nil {
 		return fmt.Errorf("could not marshal blob data: %w", err)
 	}
@@ -25,3 +25,7 @@ const (
 	LZ4
 	ZSTD
 )
+
+// DefaultCompressionLevel selects the default level of each compression
+// algorithm.
+const DefaultCompressionLevel = -1
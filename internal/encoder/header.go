@@ -24,25 +24,31 @@ import (
 	"m4o.io/pbf/v2/model"
 )
 
-func SaveHeader(wrtr io.Writer, hdr model.Header, compression BlobCompression) error {
-	bbox := hdr.BoundingBox
+func SaveHeader(wrtr io.Writer, hdr model.Header, compression BlobCompression, level int) error {
 	hb := &pb.HeaderBlock{
-		Bbox: &pb.HeaderBBox{
-			Top:    proto.Int64(bbox.Top.Coordinate()),
-			Left:   proto.Int64(bbox.Left.Coordinate()),
-			Bottom: proto.Int64(bbox.Bottom.Coordinate()),
-			Right:  proto.Int64(bbox.Right.Coordinate()),
-		},
 		RequiredFeatures:                 hdr.RequiredFeatures,
 		OptionalFeatures:                 hdr.OptionalFeatures,
 		Writingprogram:                   proto.String(hdr.WritingProgram),
 		Source:                           proto.String(hdr.Source),
-		OsmosisReplicationTimestamp:      proto.Int64(fromTimestamp(DateGranularityMs, hdr.OsmosisReplicationTimestamp)),
 		OsmosisReplicationSequenceNumber: proto.Int64(hdr.OsmosisReplicationSequenceNumber),
 		OsmosisReplicationBaseUrl:        proto.String(hdr.OsmosisReplicationBaseURL),
 	}
 
-	if err := writeBlob(wrtr, hb, compression); err != nil {
+	// the replication timestamp and bounding box are optional
+	if ts := hdr.OsmosisReplicationTimestamp; !ts.IsZero() {
+		hb.OsmosisReplicationTimestamp = proto.Int64(fromTimestamp(DateGranularityMs, ts))
+	}
+
+	if bbox := hdr.BoundingBox; bbox != nil {
+		hb.Bbox = &pb.HeaderBBox{
+			Top:    proto.Int64(bbox.Top.Coordinate()),
+			Left:   proto.Int64(bbox.Left.Coordinate()),
+			Bottom: proto.Int64(bbox.Bottom.Coordinate()),
+			Right:  proto.Int64(bbox.Right.Coordinate()),
+		}
+	}
+
+	if err := writeBlob(wrtr, hb, compression, level); err != nil {
 		return fmt.Errorf("could not write header: %w", err)
 	}
 
@@ -37,9 +37,12 @@ type Packer interface {
 	SaveTo(blob *pb.Blob)
 }
 
-// Pack marshals and compresses the blob.
-func Pack(msg proto.Message, c BlobCompression) (bb []byte, err error) {
-	p := newPacker(c)
+// Pack marshals and compresses the blob at the given compression level.
+func Pack(msg proto.Message, c BlobCompression, level int) (bb []byte, err error) {
+	p, err := newPacker(c, level)
+	if err != nil {
+		return nil, err
+	}
 
 	b, err := proto.Marshal(msg)
 	if err != nil {
@@ -68,19 +71,27 @@ func Pack(msg proto.Message, c BlobCompression) (bb []byte, err error) {
 	return bb, nil
 }
 
-// newPacker creates the appropriate Packer for the compression.
-func newPacker(c BlobCompression) Packer {
+// CheckCompression verifies that level is a valid level for the compression.
+func CheckCompression(c BlobCompression, level int) error {
+	_, err := newPacker(c, level)
+
+	return err
+}
+
+// newPacker creates the appropriate Packer for the compression and its level.
+// The level is ignored by RAW.
+func newPacker(c BlobCompression, level int) (Packer, error) {
 	switch c {
 	case RAW:
-		return packers.NewRawPacker()
+		return packers.NewRawPacker(), nil
 	case ZLIB:
-		return packers.NewZlibPacker()
+		return packers.NewZlibPacker(level)
 	case LZMA:
-		return packers.NewLzmaPacker()
+		return packers.NewLzmaPacker(level)
 	case LZ4:
-		return packers.NewLz4Packer()
+		return packers.NewLz4Packer(level), nil
 	case ZSTD:
-		return packers.NewZstdPacker()
+		return packers.NewZstdPacker(level)
 	default:
 		panic(fmt.Errorf("unknown compression type: %v", c))
 	}
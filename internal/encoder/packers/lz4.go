@@ -27,9 +27,15 @@ type Lz4Packer struct {
 	buf bytes.Buffer
 }
 
-func NewLz4Packer() *Lz4Packer {
+// NewLz4Packer creates a packer compressing at level, where higher levels
+// compress better and 0, the default when level is negative, is fastest.
+func NewLz4Packer(level int) *Lz4Packer {
 	p := Lz4Packer{}
-	p.base = newBasePacker(lz4.NewWriter(&p.buf))
+
+	w := lz4.NewWriter(&p.buf)
+	w.Header.CompressionLevel = max(level, 0)
+
+	p.base = newBasePacker(w)
 
 	return &p
 }
@@ -16,6 +16,7 @@ package packers
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/ulikunitz/xz/lzma"
 
@@ -27,17 +28,34 @@ type LzmaPacker struct {
 	buf bytes.Buffer
 }
 
-func NewLzmaPacker() *LzmaPacker {
+// lzmaDictCaps holds the dictionary capacity of each level, following the
+// presets of the xz command.
+var lzmaDictCaps = [...]int{
+	256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20, 8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20,
+}
+
+// NewLzmaPacker creates a packer compressing at level, from 0 to 9 as for the
+// xz command, or at the library's default level when level is negative.
+func NewLzmaPacker(level int) (*LzmaPacker, error) {
 	p := LzmaPacker{}
 
-	w, err := lzma.NewWriter(&p.buf)
+	var cfg lzma.WriterConfig
+
+	switch {
+	case level >= len(lzmaDictCaps):
+		return nil, fmt.Errorf("invalid lzma level %d", level)
+	case level >= 0:
+		cfg.DictCap = lzmaDictCaps[level]
+	}
+
+	w, err := cfg.NewWriter(&p.buf)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	p.base = newBasePacker(w)
 
-	return &p
+	return &p, nil
 }
 
 func (p *LzmaPacker) SaveTo(blob *pb.Blob) {
@@ -26,11 +26,19 @@ type ZlibPacker struct {
 	buf bytes.Buffer
 }
 
-func NewZlibPacker() *ZlibPacker {
+// NewZlibPacker creates a packer compressing at level, from
+// zlib.HuffmanOnly to zlib.BestCompression, or zlib.DefaultCompression.
+func NewZlibPacker(level int) (*ZlibPacker, error) {
 	p := ZlibPacker{}
-	p.base = newBasePacker(zlib.NewWriter(&p.buf))
 
-	return &p
+	w, err := zlib.NewWriterLevel(&p.buf, level)
+	if err != nil {
+		return nil, err
+	}
+
+	p.base = newBasePacker(w)
+
+	return &p, nil
 }
 
 func (p *ZlibPacker) SaveTo(blob *pb.Blob) {
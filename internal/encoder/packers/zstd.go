@@ -16,6 +16,7 @@ package packers
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/klauspost/compress/zstd"
 
@@ -27,17 +28,29 @@ type ZstdPacker struct {
 	buf bytes.Buffer
 }
 
-func NewZstdPacker() *ZstdPacker {
+// NewZstdPacker creates a packer compressing at level, from 1 to 22 as for
+// the zstd command, or at the library's default level when level is
+// negative.
+func NewZstdPacker(level int) (*ZstdPacker, error) {
 	p := ZstdPacker{}
 
-	w, err := zstd.NewWriter(&p.buf)
+	var opts []zstd.EOption
+
+	switch {
+	case level > 22 || level == 0:
+		return nil, fmt.Errorf("invalid zstd level %d", level)
+	case level > 0:
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+
+	w, err := zstd.NewWriter(&p.buf, opts...)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	p.base = newBasePacker(w)
 
-	return &p
+	return &p, nil
 }
 
 func (p *ZstdPacker) SaveTo(blob *pb.Blob) {
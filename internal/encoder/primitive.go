@@ -18,6 +18,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"time"
 
@@ -41,6 +42,27 @@ const (
 	EntityLimit = 8000
 )
 
+// Precision holds the units in which the coordinates and timestamps of a
+// block are encoded.
+type Precision struct {
+	Granularity     int32 // the size, in nano-degrees, of a coordinate unit
+	LatOffset       int64 // the latitude offset, in nano-degrees
+	LonOffset       int64 // the longitude offset, in nano-degrees
+	DateGranularity int32 // the size, in milliseconds, of a timestamp unit
+
+	// BlockOffsets replaces the offsets, for each block, with the smallest
+	// coordinates of the block.
+	BlockOffsets bool
+}
+
+// DefaultPrecision is the precision of the PBF format's defaults.
+var DefaultPrecision = Precision{
+	Granularity:     Granularity,
+	LatOffset:       LatOffset,
+	LonOffset:       LonOffset,
+	DateGranularity: DateGranularityMs,
+}
+
 func SaveBlock(w io.Writer, bb rill.Try[[]byte]) error {
 	if bb.Error != nil {
 		return bb.Error
@@ -72,12 +94,16 @@ func SaveBlock(w io.Writer, bb rill.Try[[]byte]) error {
 }
 
 type blockContext struct {
-	table    *Table
-	bbox     model.BoundingBox
-	entities []model.Entity
+	table     *Table
+	bbox      model.BoundingBox
+	entities  []model.Entity
+	precision Precision
+
+	// locationsOnWays writes the coordinates of the nodes of ways.
+	locationsOnWays bool
 }
 
-func newBlockContext(entities []model.Entity) *blockContext {
+func newBlockContext(entities []model.Entity, p Precision, locationsOnWays bool) *blockContext {
 	strings := NewStrings()
 
 	for _, e := range entities {
@@ -85,18 +111,22 @@ func newBlockContext(entities []model.Entity) *blockContext {
 
 		if r, ok := e.(*model.Relation); ok {
 			extractMemberRoles(strings, r)
-		} else if n, ok := e.(*model.Node); ok {
-			strings.Add(n.GetInfo().User)
 		}
 	}
 
 	return &blockContext{
-		table:    strings.CalcTable(),
-		entities: entities,
+		table:           strings.CalcTable(),
+		entities:        entities,
+		precision:       p,
+		locationsOnWays: locationsOnWays,
 	}
 }
 
 func (bc *blockContext) extractPrimitiveBlock() *pb.PrimitiveBlock {
+	if bc.precision.BlockOffsets {
+		bc.precision.LatOffset, bc.precision.LonOffset = bc.blockOffsets()
+	}
+
 	pg := &pb.PrimitiveGroup{}
 	switch bc.entities[0].(type) {
 	case *model.Node:
@@ -114,15 +144,59 @@ func (bc *blockContext) extractPrimitiveBlock() *pb.PrimitiveBlock {
 			S: bc.table.AsArray(),
 		},
 		Primitivegroup:  []*pb.PrimitiveGroup{pg},
-		Granularity:     proto.Int32(Granularity),
-		LatOffset:       proto.Int64(LatOffset),
-		LonOffset:       proto.Int64(LonOffset),
-		DateGranularity: proto.Int32(DateGranularityMs),
+		Granularity:     proto.Int32(bc.precision.Granularity),
+		LatOffset:       proto.Int64(bc.precision.LatOffset),
+		LonOffset:       proto.Int64(bc.precision.LonOffset),
+		DateGranularity: proto.Int32(bc.precision.DateGranularity),
 	}
 
 	return b
 }
 
+// blockOffsets returns the smallest latitude and longitude, in nano-degrees,
+// of the nodes of the block, or of the locations written for its ways,
+// rounded down to a multiple of the granularity so that no precision is lost.
+// Both are zero if the block has no coordinates.
+func (bc *blockContext) blockOffsets() (latOffset, lonOffset int64) {
+	latOffset, lonOffset = math.MaxInt64, math.MaxInt64
+
+	expand := func(lat, lon model.Degrees) {
+		latOffset = min(latOffset, model.ToCoordinate(0, 1, lat))
+		lonOffset = min(lonOffset, model.ToCoordinate(0, 1, lon))
+	}
+
+	for _, e := range bc.entities {
+		switch e := e.(type) {
+		case *model.Node:
+			expand(e.Lat, e.Lon)
+		case *model.Way:
+			if bc.locationsOnWays && len(e.Lats) == len(e.NodeIDs) && len(e.Lons) == len(e.NodeIDs) {
+				for i := range e.Lats {
+					expand(e.Lats[i], e.Lons[i])
+				}
+			}
+		}
+	}
+
+	if latOffset == math.MaxInt64 {
+		return 0, 0
+	}
+
+	g := int64(bc.precision.Granularity)
+
+	// rounds down, rather than toward zero, for negative coordinates
+	align := func(offset int64) int64 {
+		r := offset % g
+		if r < 0 {
+			r += g
+		}
+
+		return offset - r
+	}
+
+	return align(latOffset), align(lonOffset)
+}
+
 func (bc *blockContext) extractDenseNodes() *pb.DenseNodes {
 	dn := &pb.DenseNodes{}
 
@@ -140,6 +214,8 @@ func (bc *blockContext) extractDenseNodes() *pb.DenseNodes {
 
 	keyValIDs := make([]int32, 0)
 
+	p := bc.precision
+
 	for _, e := range bc.entities {
 		if n, ok := e.(*model.Node); ok {
 			ids = append(ids, int64(n.ID))
@@ -149,13 +225,13 @@ func (bc *blockContext) extractDenseNodes() *pb.DenseNodes {
 
 			bc.bbox.ExpandWithLatLng(lat, lon)
 
-			lats = append(lats, model.ToCoordinate(LatOffset, Granularity, lat))
-			lons = append(lons, model.ToCoordinate(LonOffset, Granularity, lon))
+			lats = append(lats, model.ToCoordinate(p.LatOffset, p.Granularity, lat))
+			lons = append(lons, model.ToCoordinate(p.LonOffset, p.Granularity, lon))
 
-			info := n.GetInfo()
+			info := infoOf(n)
 			versions = append(versions, info.Version)
 			uids = append(uids, int32(info.UID))
-			ts = append(ts, fromTimestamp(DateGranularityMs, info.Timestamp))
+			ts = append(ts, fromTimestamp(p.DateGranularity, info.Timestamp))
 			cs = append(cs, info.Changeset)
 			usids = append(usids, bc.table.IndexOf(info.User))
 			visible = append(visible, info.Visible)
@@ -203,10 +279,14 @@ func (bc *blockContext) extractWays() []*pb.Way {
 				Id:   proto.Int64(int64(w.ID)),
 				Keys: keyIDs,
 				Vals: valIDs,
-				Info: toInfoPb(w.Info, bc.table),
+				Info: toInfoPb(infoOf(w), bc.table, bc.precision.DateGranularity),
 				Refs: calcDeltas(refs),
 			}
 
+			if bc.locationsOnWays && len(w.Lats) == len(refs) && len(w.Lons) == len(refs) {
+				way.Lat, way.Lon = bc.extractLocations(w)
+			}
+
 			ways = append(ways, way)
 		}
 	}
@@ -214,6 +294,21 @@ func (bc *blockContext) extractWays() []*pb.Way {
 	return ways
 }
 
+// extractLocations returns the delta coded coordinates of the nodes of w.
+func (bc *blockContext) extractLocations(w *model.Way) (lats []int64, lons []int64) {
+	p := bc.precision
+
+	lats = make([]int64, len(w.Lats))
+	lons = make([]int64, len(w.Lons))
+
+	for i := range w.Lats {
+		lats[i] = model.ToCoordinate(p.LatOffset, p.Granularity, w.Lats[i])
+		lons[i] = model.ToCoordinate(p.LonOffset, p.Granularity, w.Lons[i])
+	}
+
+	return calcDeltas(lats), calcDeltas(lons)
+}
+
 func (bc *blockContext) extractRelations() []*pb.Relation {
 	var relations []*pb.Relation
 
@@ -234,7 +329,7 @@ func (bc *blockContext) extractRelations() []*pb.Relation {
 				Id:       proto.Int64(int64(r.ID)),
 				Keys:     keyIDs,
 				Vals:     valIDs,
-				Info:     toInfoPb(r.Info, bc.table),
+				Info:     toInfoPb(infoOf(r), bc.table, bc.precision.DateGranularity),
 				RolesSid: roleids,
 				Memids:   calcDeltas(memids),
 				Types:    types,
@@ -296,10 +391,20 @@ func calcTagIDs(tags map[string]string, table *Table) (keyIDs []uint32, valIDs [
 	return keyIDs, valIDs
 }
 
-func toInfoPb(info *model.Info, table *Table) *pb.Info {
+// infoOf returns the info of e or, if it has none, as for entities read
+// without metadata, a visible info whose other fields are zero.
+func infoOf(e model.Entity) *model.Info {
+	if info := e.GetInfo(); info != nil {
+		return info
+	}
+
+	return &model.Info{Visible: true}
+}
+
+func toInfoPb(info *model.Info, table *Table, dateGranularity int32) *pb.Info {
 	pbInfo := &pb.Info{
 		Version:   proto.Int32(info.Version),
-		Timestamp: proto.Int32(int32(info.Timestamp.UTC().UnixMilli() / DateGranularityMs)),
+		Timestamp: proto.Int32(int32(fromTimestamp(dateGranularity, info.Timestamp))),
 		Changeset: proto.Int64(info.Changeset),
 		Uid:       proto.Int32(int32(info.UID)),
 		UserSid:   proto.Int32(table.IndexOf(info.User)),
@@ -309,9 +414,14 @@ func toInfoPb(info *model.Info, table *Table) *pb.Info {
 	return pbInfo
 }
 
-// fromTimestamp converts a timestamp with a specific granularity, in units of
-// milliseconds, to a UTC timestamp of type Time.
+// fromTimestamp converts a timestamp of type Time to one with a specific
+// granularity, in units of milliseconds.  The zero Time, of entities without
+// a timestamp, is converted to 0, which readers take as no timestamp.
 func fromTimestamp(granularity int32, timestamp time.Time) int64 {
+	if timestamp.IsZero() {
+		return 0
+	}
+
 	millis := timestamp.UnixMilli()
 
 	return millis / int64(granularity)
@@ -47,4 +47,60 @@ func TestFromTimestamp(t *testing.T) {
 
 	assert.Equal(t, int64(1644784822), fromTimestamp(DateGranularityMs, ts))
 	assert.Equal(t, int64(1644784822), fromTimestamp(DateGranularityMs, ts.Local()))
+	assert.Equal(t, int64(0), fromTimestamp(DateGranularityMs, time.Time{}))
+}
+
+func TestExtractPrimitiveBlockWithoutInfo(t *testing.T) {
+	test_cases := []struct {
+		name   string
+		entity model.Entity
+	}{
+		{"node", &model.Node{ID: 1, Lat: 51.5, Lon: -0.1}},
+		{"way", &model.Way{ID: 1, NodeIDs: []model.ID{1, 2}}},
+		{"relation", &model.Relation{ID: 1, Members: []model.Member{{ID: 1, Type: model.WAY}}}},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.name, func(t *testing.T) {
+			block := newBlockContext([]model.Entity{tc.entity}, DefaultPrecision, false).extractPrimitiveBlock()
+			pg := block.GetPrimitivegroup()[0]
+
+			switch {
+			case pg.GetDense() != nil:
+				di := pg.GetDense().GetDenseinfo()
+				assert.Equal(t, []int32{0}, di.GetVersion())
+				assert.Equal(t, []int64{0}, di.GetTimestamp())
+				assert.Equal(t, []bool{true}, di.GetVisible())
+			case len(pg.GetWays()) > 0:
+				assert.Zero(t, pg.GetWays()[0].GetInfo().GetVersion())
+				assert.True(t, pg.GetWays()[0].GetInfo().GetVisible())
+			default:
+				assert.Zero(t, pg.GetRelations()[0].GetInfo().GetVersion())
+				assert.True(t, pg.GetRelations()[0].GetInfo().GetVisible())
+			}
+		})
+	}
+}
+
+func TestBlockOffsets(t *testing.T) {
+	p := DefaultPrecision
+	p.BlockOffsets = true
+
+	nodes := []model.Entity{
+		&model.Node{ID: 1, Lat: 51.5000123, Lon: -0.1000456, Info: &model.Info{}},
+		&model.Node{ID: 2, Lat: 51.4000789, Lon: -0.2000321, Info: &model.Info{}},
+	}
+
+	block := newBlockContext(nodes, p, false).extractPrimitiveBlock()
+
+	// the smallest coordinates, rounded down to the granularity
+	assert.Equal(t, int64(51_400_078_900), block.GetLatOffset())
+	assert.Equal(t, int64(-200_032_100), block.GetLonOffset())
+
+	dense := block.GetPrimitivegroup()[0].GetDense()
+	assert.Equal(t, []int64{999_334, -999_334}, dense.GetLat())
+	assert.Equal(t, []int64{999_865, -999_865}, dense.GetLon())
+
+	lat := model.ToDegrees(block.GetLatOffset(), block.GetGranularity(), dense.GetLat()[0])
+	assert.InDelta(t, 51.5000123, float64(lat), 1e-9)
 }
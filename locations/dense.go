@@ -0,0 +1,140 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package locations
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"m4o.io/pbf/v2/model"
+)
+
+const (
+	// slotSize is the size of the location of a node in a Dense store.
+	slotSize = 8
+
+	// initialSlots is the number of slots a Dense store is created with.
+	initialSlots = 1 << 20
+
+	// unsetLat flips the sign bit of latitudes, so that the zeroes of an
+	// unset slot decode to a latitude out of range.
+	unsetLat = 1 << 31
+)
+
+// Dense is a Store backed by a memory-mapped temporary file, holding the
+// location of node n at offset 8n.  The file grows to cover the largest ID
+// stored; it is sparse on most file systems, so Dense suits inputs whose
+// node IDs are dense, such as the planet.  Negative IDs cannot be stored.
+// Dense is only available on Unix systems.
+type Dense struct {
+	f    *os.File
+	data []byte // the mapped file
+}
+
+var _ Store = (*Dense)(nil)
+
+// NewDense returns an empty Dense store, backed by a file created in dir, or
+// in the default directory for temporary files if dir is empty.  The file is
+// removed when the store is closed.
+func NewDense(dir string) (*Dense, error) {
+	f, err := os.CreateTemp(dir, "locations-*.dense")
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Dense{f: f}
+	if err := d.grow(initialSlots); err != nil {
+		return nil, errors.Join(err, f.Close(), os.Remove(f.Name()))
+	}
+
+	return d, nil
+}
+
+// Set stores the location of the node id.
+func (d *Dense) Set(id model.ID, lat, lon model.Degrees) error {
+	if id < 0 {
+		return fmt.Errorf("%w: %d", ErrNegativeID, id)
+	}
+
+	if slots := d.slots(); int64(id) >= slots {
+		if err := d.grow(max(2*slots, int64(id)+1)); err != nil {
+			return err
+		}
+	}
+
+	off := int64(id) * slotSize
+	binary.LittleEndian.PutUint32(d.data[off:], uint32(toE7(lat))^unsetLat)
+	binary.LittleEndian.PutUint32(d.data[off+4:], uint32(toE7(lon)))
+
+	return nil
+}
+
+// Lookup returns the location of the node id, and whether it is stored.
+func (d *Dense) Lookup(id model.ID) (lat, lon model.Degrees, ok bool) {
+	if id < 0 || int64(id) >= d.slots() {
+		return 0, 0, false
+	}
+
+	off := int64(id) * slotSize
+
+	rawLat := binary.LittleEndian.Uint32(d.data[off:])
+	if rawLat == 0 {
+		return 0, 0, false
+	}
+
+	rawLon := binary.LittleEndian.Uint32(d.data[off+4:])
+
+	return fromE7(int32(rawLat ^ unsetLat)), fromE7(int32(rawLon)), true
+}
+
+// Close unmaps and removes the backing file.
+func (d *Dense) Close() error {
+	return errors.Join(syscall.Munmap(d.data), d.f.Close(), os.Remove(d.f.Name()))
+}
+
+// slots returns the number of slots of the mapped file.
+func (d *Dense) slots() int64 {
+	return int64(len(d.data)) / slotSize
+}
+
+// grow extends the backing file to hold slots locations and maps it again.
+func (d *Dense) grow(slots int64) error {
+	if d.data != nil {
+		if err := syscall.Munmap(d.data); err != nil {
+			return err
+		}
+
+		d.data = nil
+	}
+
+	size := slots * slotSize
+	if err := d.f.Truncate(size); err != nil {
+		return err
+	}
+
+	data, err := syscall.Mmap(int(d.f.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+
+	d.data = data
+
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !unix
+
+package locations
+
+import "m4o.io/pbf/v2/model"
+
+// Dense is a Store backed by a memory-mapped temporary file.  It is only
+// available on Unix systems; elsewhere, NewDense fails with
+// ErrDenseUnsupported.
+type Dense struct{}
+
+var _ Store = (*Dense)(nil)
+
+// NewDense fails with ErrDenseUnsupported, since Dense is only available on
+// Unix systems.
+func NewDense(string) (*Dense, error) {
+	return nil, ErrDenseUnsupported
+}
+
+// Set fails with ErrDenseUnsupported.
+func (d *Dense) Set(model.ID, model.Degrees, model.Degrees) error {
+	return ErrDenseUnsupported
+}
+
+// Lookup reports that no location is stored.
+func (d *Dense) Lookup(model.ID) (lat, lon model.Degrees, ok bool) {
+	return 0, 0, false
+}
+
+// Close does nothing.
+func (d *Dense) Close() error {
+	return nil
+}
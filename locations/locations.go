@@ -0,0 +1,94 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package locations stores the coordinates of nodes so that the geometry of
+ways can be assembled from their node IDs.
+
+Three stores are provided, suited to different amounts of data:
+
+	SparseMap    an in-memory map, for extracts with few nodes
+	Dense        a memory-mapped file indexed by ID, for planet sized inputs,
+	             on Unix systems only
+	SortedArray  a file of records sorted by ID, for inputs sorted by ID
+
+A store is filled from the nodes of a decoder, for example
+
+	store := locations.NewSparseMap()
+	err := locations.Fill(store, dec.All())
+
+and can then be looked up concurrently.  Stores are not safe for concurrent
+use while they are being filled.  Coordinates are held to seven decimal
+places, as in the OSM database.
+*/
+package locations
+
+import (
+	"errors"
+	"iter"
+
+	"m4o.io/pbf/v2/model"
+)
+
+var (
+	// ErrNegativeID is returned when a store indexed by ID is given a node
+	// with a negative ID.
+	ErrNegativeID = errors.New("negative node ID")
+	// ErrUnsorted is returned when a SortedArray is given nodes out of ID
+	// order.
+	ErrUnsorted = errors.New("node IDs are not in ascending order")
+	// ErrDenseUnsupported is returned by NewDense on systems that cannot
+	// memory-map its backing file.
+	ErrDenseUnsupported = errors.New("dense stores are only available on Unix systems")
+)
+
+// Store is a store of node locations.
+type Store interface {
+	// Set stores the location of the node id.
+	Set(id model.ID, lat, lon model.Degrees) error
+
+	// Lookup returns the location of the node id, and whether it is stored.
+	Lookup(id model.ID) (lat, lon model.Degrees, ok bool)
+
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// Fill stores the location of every node of entities, as returned by
+// Decoder.All or Decoder.Nodes, in s.  Entities of other types are skipped.
+func Fill[E model.Entity](s Store, entities iter.Seq2[E, error]) error {
+	for e, err := range entities {
+		if err != nil {
+			return err
+		}
+
+		if n, ok := any(e).(*model.Node); ok {
+			if err := s.Set(n.ID, n.Lat, n.Lon); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// toE7 converts degrees to ten millionths of degrees.
+func toE7(d model.Degrees) int32 {
+	return d.E7()
+}
+
+// fromE7 converts ten millionths of degrees to degrees.
+func fromE7(e7 int32) model.Degrees {
+	return model.Degrees(float64(e7) / model.TenMillionths)
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locations_test
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/locations"
+	"m4o.io/pbf/v2/model"
+)
+
+func stores(t *testing.T) map[string]locations.Store {
+	t.Helper()
+
+	sorted, err := locations.NewSortedArray(t.TempDir())
+	require.NoError(t, err)
+
+	stores := map[string]locations.Store{
+		"sparse": locations.NewSparseMap(),
+		"sorted": sorted,
+	}
+
+	if dense, err := locations.NewDense(t.TempDir()); !errors.Is(err, locations.ErrDenseUnsupported) {
+		require.NoError(t, err)
+
+		stores["dense"] = dense
+	}
+
+	return stores
+}
+
+func TestSetAndLookup(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			defer func() { require.NoError(t, s.Close()) }()
+
+			require.NoError(t, s.Set(1, 0, 0))
+			require.NoError(t, s.Set(5, -33.8688197, 151.2092955))
+			require.NoError(t, s.Set(3_000_000, 90, -180))
+
+			lat, lon, ok := s.Lookup(1)
+			require.True(t, ok, "null island")
+			assert.Equal(t, model.Degrees(0), lat)
+			assert.Equal(t, model.Degrees(0), lon)
+
+			lat, lon, ok = s.Lookup(5)
+			require.True(t, ok)
+			assert.True(t, lat.EqualWithin(-33.8688197, model.E7))
+			assert.True(t, lon.EqualWithin(151.2092955, model.E7))
+
+			lat, lon, ok = s.Lookup(3_000_000)
+			require.True(t, ok)
+			assert.True(t, lat.EqualWithin(90, model.E7))
+			assert.True(t, lon.EqualWithin(-180, model.E7))
+
+			for _, id := range []model.ID{-1, 0, 2, 6, 3_000_001} {
+				_, _, ok := s.Lookup(id)
+				assert.False(t, ok, "node %d", id)
+			}
+		})
+	}
+}
+
+func TestFillFromDecoder(t *testing.T) {
+	s := locations.NewSparseMap()
+	defer s.Close()
+
+	require.NoError(t, locations.Fill(s, decodeSample(t).All()))
+
+	assert.Equal(t, 290, s.Len())
+}
+
+func TestFill(t *testing.T) {
+	var nodes []*model.Node
+
+	for node, err := range decodeSample(t).Nodes() {
+		require.NoError(t, err)
+
+		nodes = append(nodes, node)
+	}
+
+	// the sample is not sorted, as a SortedArray requires
+	slices.SortFunc(nodes, func(a, b *model.Node) int { return cmp.Compare(a.ID, b.ID) })
+
+	all := func(yield func(*model.Node, error) bool) {
+		for _, node := range nodes {
+			if !yield(node, nil) {
+				return
+			}
+		}
+	}
+
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			defer func() { require.NoError(t, s.Close()) }()
+
+			require.NoError(t, locations.Fill(s, all))
+
+			for _, node := range nodes {
+				lat, lon, ok := s.Lookup(node.ID)
+				require.True(t, ok, "node %d", node.ID)
+				assert.True(t, lat.EqualWithin(node.Lat, model.E7))
+				assert.True(t, lon.EqualWithin(node.Lon, model.E7))
+			}
+		})
+	}
+}
+
+func TestSortedArrayRejectsUnsortedIDs(t *testing.T) {
+	s, err := locations.NewSortedArray(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Set(2, 1, 1))
+	assert.ErrorIs(t, s.Set(1, 1, 1), locations.ErrUnsorted)
+	assert.ErrorIs(t, s.Set(2, 1, 1), locations.ErrUnsorted)
+}
+
+func TestCloseRemovesBackingFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	sorted, err := locations.NewSortedArray(dir)
+	require.NoError(t, err)
+	require.NoError(t, sorted.Close())
+
+	if dense, err := locations.NewDense(dir); !errors.Is(err, locations.ErrDenseUnsupported) {
+		require.NoError(t, err)
+		require.NoError(t, dense.Close())
+	}
+
+	left, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, left)
+}
+
+func decodeSample(t *testing.T) *pbf.Decoder {
+	t.Helper()
+
+	in, err := os.Open("../testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	t.Cleanup(func() { in.Close() })
+
+	dec, err := pbf.NewDecoder(context.Background(), in)
+	require.NoError(t, err)
+
+	return dec
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locations
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// recordSize is the size of the ID and location of a node in a SortedArray.
+const recordSize = 16
+
+// SortedArray is a Store backed by a temporary file of records sorted by ID,
+// which are binary searched on lookup.  It uses disk in proportion to the
+// number of stored nodes and little memory, but nodes must be set in
+// ascending ID order, as they are by inputs declaring Sort.Type_then_ID when
+// decoded in file order by NewDecoderAt, or by NewDecoder with WithOrdered.
+type SortedArray struct {
+	f *os.File
+	w *bufio.Writer
+
+	mu   sync.Mutex // guards flushing w before lookups
+	n    int        // the number of records
+	last model.ID   // the ID of the last record
+}
+
+var _ Store = (*SortedArray)(nil)
+
+// NewSortedArray returns an empty SortedArray, backed by a file created in
+// dir, or in the default directory for temporary files if dir is empty.  The
+// file is removed when the store is closed.
+func NewSortedArray(dir string) (*SortedArray, error) {
+	f, err := os.CreateTemp(dir, "locations-*.sorted")
+	if err != nil {
+		return nil, err
+	}
+
+	return &SortedArray{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// Set stores the location of the node id, which must be greater than that
+// of the previous node set, or fails with ErrUnsorted.
+func (a *SortedArray) Set(id model.ID, lat, lon model.Degrees) error {
+	if a.n > 0 && id <= a.last {
+		return fmt.Errorf("%w: %d after %d", ErrUnsorted, id, a.last)
+	}
+
+	var rec [recordSize]byte
+
+	binary.LittleEndian.PutUint64(rec[:], uint64(id))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(toE7(lat)))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(toE7(lon)))
+
+	if _, err := a.w.Write(rec[:]); err != nil {
+		return err
+	}
+
+	a.n++
+	a.last = id
+
+	return nil
+}
+
+// Lookup returns the location of the node id, and whether it is stored.
+// A node that cannot be read from the backing file is reported as not
+// stored.
+func (a *SortedArray) Lookup(id model.ID) (lat, lon model.Degrees, ok bool) {
+	if err := a.flush(); err != nil {
+		return 0, 0, false
+	}
+
+	var rec [recordSize]byte
+
+	readID := func(i int) (model.ID, error) {
+		if _, err := a.f.ReadAt(rec[:8], int64(i)*recordSize); err != nil {
+			return 0, err
+		}
+
+		return model.ID(binary.LittleEndian.Uint64(rec[:])), nil
+	}
+
+	var err error
+
+	i := sort.Search(a.n, func(i int) bool {
+		found, readErr := readID(i)
+		err = errors.Join(err, readErr)
+
+		return found >= id
+	})
+
+	if err != nil || i == a.n {
+		return 0, 0, false
+	}
+
+	if _, err := a.f.ReadAt(rec[:], int64(i)*recordSize); err != nil {
+		return 0, 0, false
+	}
+
+	if model.ID(binary.LittleEndian.Uint64(rec[:])) != id {
+		return 0, 0, false
+	}
+
+	lat = fromE7(int32(binary.LittleEndian.Uint32(rec[8:])))
+	lon = fromE7(int32(binary.LittleEndian.Uint32(rec[12:])))
+
+	return lat, lon, true
+}
+
+// Len returns the number of stored nodes.
+func (a *SortedArray) Len() int {
+	return a.n
+}
+
+// Close removes the backing file.
+func (a *SortedArray) Close() error {
+	return errors.Join(a.f.Close(), os.Remove(a.f.Name()))
+}
+
+// flush writes any buffered records to the backing file.
+func (a *SortedArray) flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.w.Buffered() == 0 {
+		return nil
+	}
+
+	return a.w.Flush()
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locations
+
+import "m4o.io/pbf/v2/model"
+
+// location is a node location in ten millionths of degrees.
+type location struct {
+	lat, lon int32
+}
+
+// SparseMap is an in-memory Store backed by a map.  It uses memory in
+// proportion to the number of stored nodes, and so suits extracts.
+type SparseMap struct {
+	locations map[model.ID]location
+}
+
+var _ Store = (*SparseMap)(nil)
+
+// NewSparseMap returns an empty SparseMap.
+func NewSparseMap() *SparseMap {
+	return &SparseMap{locations: make(map[model.ID]location)}
+}
+
+// Set stores the location of the node id.
+func (m *SparseMap) Set(id model.ID, lat, lon model.Degrees) error {
+	m.locations[id] = location{toE7(lat), toE7(lon)}
+
+	return nil
+}
+
+// Lookup returns the location of the node id, and whether it is stored.
+func (m *SparseMap) Lookup(id model.ID) (lat, lon model.Degrees, ok bool) {
+	loc, ok := m.locations[id]
+	if !ok {
+		return 0, 0, false
+	}
+
+	return fromE7(loc.lat), fromE7(loc.lon), true
+}
+
+// Len returns the number of stored nodes.
+func (m *SparseMap) Len() int {
+	return len(m.locations)
+}
+
+// Close releases the stored locations.
+func (m *SparseMap) Close() error {
+	m.locations = nil
+
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+//go:generate stringer -type=Action
+
+// Action is an enumeration of the ways an OsmChange alters an entity.
+type Action int32
+
+const (
+	// CREATE denotes that the entity is new.
+	CREATE Action = iota
+
+	// MODIFY denotes that the entity replaces an earlier version.
+	MODIFY
+
+	// DELETE denotes that the entity, which holds the last version, is
+	// removed.
+	DELETE
+)
+
+// Change is an entity created, modified or deleted by an OsmChange.
+type Change struct {
+	Action Action
+	Entity Entity
+}
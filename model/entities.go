@@ -18,6 +18,7 @@ package model
 //go:generate stringer -type=EntityType
 
 import (
+	"cmp"
 	"time"
 )
 
@@ -75,11 +76,16 @@ func (r Node) GetInfo() *Info {
 }
 
 // Way is an ordered list of between 2 and 2,000 nodes that define a polyline.
+// When the input declares the LocationsOnWays feature, Lats and Lons hold
+// the coordinates of the nodes, in the order of NodeIDs; otherwise they are
+// nil.
 type Way struct {
 	ID      ID
 	Tags    map[string]string
 	Info    *Info
 	NodeIDs []ID
+	Lats    []Degrees
+	Lons    []Degrees
 }
 
 var _ Entity = Way{}
@@ -112,6 +118,40 @@ const (
 	RELATION
 )
 
+// TypeOf returns the type of the entity e.
+func TypeOf(e Entity) EntityType {
+	switch e.(type) {
+	case *Node, Node:
+		return NODE
+	case *Way, Way:
+		return WAY
+	default:
+		return RELATION
+	}
+}
+
+// Compare orders entities by type, nodes first, then ID.  It returns a
+// negative number when a precedes b, a positive number when b precedes a and
+// zero when they share a type and ID.
+func Compare(a, b Entity) int {
+	return cmp.Or(cmp.Compare(TypeOf(a), TypeOf(b)), cmp.Compare(a.GetID(), b.GetID()))
+}
+
+// CompareVersions orders entities as Compare does, then by version, so that
+// the versions of an entity are ordered oldest first.
+func CompareVersions(a, b Entity) int {
+	return cmp.Or(Compare(a, b), cmp.Compare(Version(a), Version(b)))
+}
+
+// Version returns the version of the entity e, or zero if it has no info.
+func Version(e Entity) int32 {
+	if info := e.GetInfo(); info != nil {
+		return info.Version
+	}
+
+	return 0
+}
+
 // Member represents an entity that.
 type Member struct {
 	ID   ID
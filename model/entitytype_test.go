@@ -28,3 +28,25 @@ func TestEntityTypeString(t *testing.T) {
 	assert.Equal(t, "EntityType(-1)", (NODE - 1).String())
 	assert.Equal(t, "EntityType(3)", (RELATION + 1).String())
 }
+
+func TestActionString(t *testing.T) {
+	assert.Equal(t, "CREATE", CREATE.String())
+	assert.Equal(t, "MODIFY", MODIFY.String())
+	assert.Equal(t, "DELETE", DELETE.String())
+
+	assert.Equal(t, "Action(3)", (DELETE + 1).String())
+}
+
+func TestCompare(t *testing.T) {
+	assert.Negative(t, Compare(&Node{ID: 9}, &Way{ID: 1}))
+	assert.Negative(t, Compare(&Way{ID: 1}, &Way{ID: 2}))
+	assert.Positive(t, Compare(&Relation{ID: 1}, &Way{ID: 2}))
+	assert.Zero(t, Compare(&Relation{ID: 3}, &Relation{ID: 3}))
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Negative(t, CompareVersions(&Node{ID: 1, Info: &Info{Version: 2}}, &Node{ID: 2, Info: &Info{Version: 1}}))
+	assert.Negative(t, CompareVersions(&Way{ID: 1}, &Way{ID: 1, Info: &Info{Version: 1}}))
+	assert.Positive(t, CompareVersions(&Way{ID: 1, Info: &Info{Version: 3}}, &Way{ID: 1, Info: &Info{Version: 2}}))
+	assert.Zero(t, CompareVersions(&Relation{ID: 3, Info: &Info{Version: 1}}, &Relation{ID: 3, Info: &Info{Version: 1}}))
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"maps"
+	"slices"
+)
+
+// Equal reports whether the entities a and b are of the same type and hold
+// the same ID, tags, info and, depending on the type, location, nodes or
+// members.  Nil and empty tags are equal, as are nil and empty lists.  Only
+// pointers to entities, as decoded, are compared.
+func Equal(a, b Entity) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if TypeOf(a) != TypeOf(b) || a.GetID() != b.GetID() ||
+		!maps.Equal(a.GetTags(), b.GetTags()) || !a.GetInfo().Equal(b.GetInfo()) {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Node:
+		b, ok := b.(*Node)
+
+		return ok && a.Lat == b.Lat && a.Lon == b.Lon
+	case *Way:
+		b, ok := b.(*Way)
+
+		return ok && slices.Equal(a.NodeIDs, b.NodeIDs) && slices.Equal(a.Lats, b.Lats) && slices.Equal(a.Lons, b.Lons)
+	case *Relation:
+		b, ok := b.(*Relation)
+
+		return ok && slices.Equal(a.Members, b.Members)
+	}
+
+	return false
+}
+
+// Equal reports whether the infos i and o are equal; timestamps are equal
+// when they are the same instant.
+func (i *Info) Equal(o *Info) bool {
+	if i == nil || o == nil {
+		return i == o
+	}
+
+	return i.Version == o.Version && i.UID == o.UID && i.Timestamp.Equal(o.Timestamp) &&
+		i.Changeset == o.Changeset && i.User == o.User && i.Visible == o.Visible
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"m4o.io/pbf/v2/model"
+)
+
+func TestEqual(t *testing.T) {
+	ts := time.Date(2024, 10, 28, 21, 21, 30, 0, time.UTC)
+	info := func() *model.Info {
+		return &model.Info{Version: 2, UID: 3, Timestamp: ts, Changeset: 4, User: "u", Visible: true}
+	}
+
+	node := &model.Node{ID: 1, Tags: map[string]string{"a": "b"}, Info: info(), Lat: 1, Lon: 2}
+	way := &model.Way{ID: 1, Info: info(), NodeIDs: []model.ID{1, 2}}
+	relation := &model.Relation{ID: 1, Info: info(), Members: []model.Member{{ID: 1, Type: model.WAY, Role: "outer"}}}
+
+	test_cases := []struct {
+		name  string
+		a, b  model.Entity
+		equal bool
+	}{
+		{"same node", node, &model.Node{ID: 1, Tags: map[string]string{"a": "b"}, Info: info(), Lat: 1, Lon: 2}, true},
+		{"moved node", node, &model.Node{ID: 1, Tags: map[string]string{"a": "b"}, Info: info(), Lat: 1, Lon: 3}, false},
+		{"retagged node", node, &model.Node{ID: 1, Tags: map[string]string{"a": "c"}, Info: info(), Lat: 1, Lon: 2}, false},
+		{"local timestamp", node, &model.Node{
+			ID: 1, Tags: map[string]string{"a": "b"}, Lat: 1, Lon: 2,
+			Info: &model.Info{Version: 2, UID: 3, Timestamp: ts.Local(), Changeset: 4, User: "u", Visible: true},
+		}, true},
+		{"new version", node, &model.Node{
+			ID: 1, Tags: map[string]string{"a": "b"}, Lat: 1, Lon: 2,
+			Info: &model.Info{Version: 3, UID: 3, Timestamp: ts, Changeset: 4, User: "u", Visible: true},
+		}, false},
+		{"missing info", node, &model.Node{ID: 1, Tags: map[string]string{"a": "b"}, Lat: 1, Lon: 2}, false},
+		{"empty tags", way, &model.Way{ID: 1, Tags: map[string]string{}, Info: info(), NodeIDs: []model.ID{1, 2}}, true},
+		{"reordered nodes", way, &model.Way{ID: 1, Info: info(), NodeIDs: []model.ID{2, 1}}, false},
+		{"same relation", relation, &model.Relation{
+			ID: 1, Info: info(), Members: []model.Member{{ID: 1, Type: model.WAY, Role: "outer"}},
+		}, true},
+		{"new role", relation, &model.Relation{
+			ID: 1, Info: info(), Members: []model.Member{{ID: 1, Type: model.WAY, Role: "inner"}},
+		}, false},
+		{"other type", node, &model.Way{ID: 1, Tags: map[string]string{"a": "b"}, Info: info()}, false},
+		{"nil", node, nil, false},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.equal, model.Equal(tc.a, tc.b))
+			assert.Equal(t, tc.equal, model.Equal(tc.b, tc.a))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+// Point is a location on the earth's surface.
+type Point struct {
+	Lat Degrees `json:"lat"`
+	Lon Degrees `json:"lon"`
+}
+
+// LineString is a sequence of points joined by straight lines.
+type LineString []Point
+
+// Ring is a closed sequence of points.  The last point is implicitly joined
+// to the first.
+type Ring []Point
+
+// Polygon is an area bounded by an outer ring, less any holes described by
+// its inner rings.
+type Polygon struct {
+	Outer Ring   `json:"outer"`
+	Inner []Ring `json:"inner,omitempty"`
+}
+
+// MultiPolygon is a set of polygons that neither overlap nor share edges.
+type MultiPolygon []Polygon
+
+// BoundingBox returns the bounding box of the outer ring.
+func (p *Polygon) BoundingBox() *BoundingBox {
+	bbox := InitialBoundingBox()
+
+	for _, pt := range p.Outer {
+		bbox.ExpandWithLatLng(pt.Lat, pt.Lon)
+	}
+
+	return bbox
+}
+
+// Contains checks if the polygon contains the lat lng point.  Points within
+// a hole are not contained by the polygon.
+func (p *Polygon) Contains(lat Degrees, lon Degrees) bool {
+	if !p.Outer.Contains(lat, lon) {
+		return false
+	}
+
+	for _, hole := range p.Inner {
+		if hole.Contains(lat, lon) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Contains checks if the ring encloses the lat lng point, using the even-odd
+// rule.
+func (r Ring) Contains(lat Degrees, lon Degrees) bool {
+	inside := false
+
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[i], r[j]
+
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			lon < (b.Lon-a.Lon)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"m4o.io/pbf/v2/model"
+)
+
+func TestPolygonContains(t *testing.T) {
+	square := &model.Polygon{
+		Outer: model.Ring{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 10}, {Lat: 10, Lon: 10}, {Lat: 10, Lon: 0}},
+		Inner: []model.Ring{
+			{{Lat: 4, Lon: 4}, {Lat: 4, Lon: 6}, {Lat: 6, Lon: 6}, {Lat: 6, Lon: 4}},
+		},
+	}
+
+	test_cases := []struct {
+		name     string
+		lat      model.Degrees
+		lon      model.Degrees
+		expected bool
+	}{
+		{"inside", 2, 2, true},
+		{"in hole", 5, 5, false},
+		{"between hole and edge", 5, 8, true},
+		{"outside east", 5, 11, false},
+		{"outside south", -1, 5, false},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, square.Contains(tc.lat, tc.lon))
+		})
+	}
+}
+
+func TestPolygonContainsConcave(t *testing.T) {
+	// A "U" shape, open to the north.
+	u := &model.Polygon{
+		Outer: model.Ring{
+			{Lat: 0, Lon: 0}, {Lat: 0, Lon: 3}, {Lat: 3, Lon: 3}, {Lat: 3, Lon: 2},
+			{Lat: 1, Lon: 2}, {Lat: 1, Lon: 1}, {Lat: 3, Lon: 1}, {Lat: 3, Lon: 0},
+		},
+	}
+
+	assert.True(t, u.Contains(2, 0.5))
+	assert.True(t, u.Contains(2, 2.5))
+	assert.False(t, u.Contains(2, 1.5))
+	assert.True(t, u.Contains(0.5, 1.5))
+}
+
+func TestPolygonBoundingBox(t *testing.T) {
+	p := &model.Polygon{
+		Outer: model.Ring{{Lat: -1, Lon: 2}, {Lat: 3, Lon: 2}, {Lat: 1, Lon: -4}},
+	}
+
+	assert.Equal(t, &model.BoundingBox{Top: 3, Left: -4, Bottom: -1, Right: 2}, p.BoundingBox())
+}
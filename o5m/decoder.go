@@ -0,0 +1,464 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package o5m
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// BatchSize is the max number of entities returned by Decoder.Decode.
+const BatchSize = 8000
+
+// Decoder reads o5m, or o5c, from an input stream.
+type Decoder struct {
+	Header model.Header
+
+	// Change is set when the input is an o5c change file.
+	Change bool
+
+	r      *bufio.Reader
+	buf    []byte
+	deltas deltas
+	table  stringTable
+	done   bool // the end dataset has been read
+}
+
+// NewDecoder returns a new decoder that reads from rdr.  The decoder is
+// initialized with the header, bounding box and timestamp datasets.
+func NewDecoder(rdr io.Reader) (*Decoder, error) {
+	d := &Decoder{r: bufio.NewReader(rdr)}
+
+	if typ, err := d.r.ReadByte(); err != nil || typ != resetDataset {
+		return nil, fmt.Errorf("%w: missing reset", ErrInvalid)
+	}
+
+	typ, p, err := d.dataset()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case typ != headerDataset:
+		return nil, fmt.Errorf("%w: missing header", ErrInvalid)
+	case string(p) == o5mHeader:
+	case string(p) == o5cHeader:
+		d.Change = true
+	default:
+		return nil, fmt.Errorf("%w: unsupported header %q", ErrInvalid, p)
+	}
+
+	for {
+		next, err := d.r.Peek(1)
+		if errors.Is(err, io.EOF) {
+			return d, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if next[0] != bboxDataset && next[0] != timestampDataset {
+			return d, nil
+		}
+
+		typ, p, err := d.dataset()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := d.decodeHeader(typ, p); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Decode reads the next batch of at most BatchSize entities, in input order.
+// The end of the input is reported by an io.EOF error.
+func (d *Decoder) Decode() ([]model.Entity, error) {
+	var entities []model.Entity
+
+	for len(entities) < BatchSize && !d.done {
+		typ, p, err := d.dataset()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var e model.Entity
+
+		switch typ {
+		case nodeDataset:
+			e, err = d.node(p)
+		case wayDataset:
+			e, err = d.way(p)
+		case relationDataset:
+			e, err = d.relation(p)
+		case resetDataset:
+			d.deltas = deltas{}
+			d.table.reset()
+		case endDataset:
+			d.done = true
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if e != nil {
+			entities = append(entities, e)
+		}
+	}
+
+	if len(entities) == 0 {
+		return nil, io.EOF
+	}
+
+	return entities, nil
+}
+
+// All returns an iterator over the decoded entities.  Iteration ends at the
+// end of the input or once an error has been yielded.
+func (d *Decoder) All() iter.Seq2[model.Entity, error] {
+	return func(yield func(model.Entity, error) bool) {
+		for {
+			entities, err := d.Decode()
+			if errors.Is(err, io.EOF) {
+				return
+			} else if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			for _, e := range entities {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Changes returns an iterator over the decoded entities as changes: deleted
+// entities are deleted, entities at version one created and others modified.
+func (d *Decoder) Changes() iter.Seq2[model.Change, error] {
+	return func(yield func(model.Change, error) bool) {
+		for e, err := range d.All() {
+			if err != nil {
+				yield(model.Change{}, err)
+
+				return
+			}
+
+			c := model.Change{Action: model.MODIFY, Entity: e}
+
+			switch info := e.GetInfo(); {
+			case info == nil:
+			case !info.Visible:
+				c.Action = model.DELETE
+			case info.Version == 1:
+				c.Action = model.CREATE
+			}
+
+			if !yield(c, nil) {
+				return
+			}
+		}
+	}
+}
+
+// dataset reads the type and payload of the next dataset.  The payload is
+// only valid until the next call.
+func (d *Decoder) dataset() (byte, payload, error) {
+	typ, err := d.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if typ >= singleDatasets {
+		return typ, nil, nil
+	}
+
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: bad dataset length: %w", ErrInvalid, err)
+	}
+
+	if uint64(cap(d.buf)) < n {
+		d.buf = make([]byte, n)
+	}
+
+	d.buf = d.buf[:n]
+
+	if _, err := io.ReadFull(d.r, d.buf); err != nil {
+		return 0, nil, fmt.Errorf("%w: truncated dataset: %w", ErrInvalid, err)
+	}
+
+	return typ, d.buf, nil
+}
+
+// decodeHeader decodes the bounding box and timestamp datasets.
+func (d *Decoder) decodeHeader(typ byte, p payload) error {
+	if typ == timestampDataset {
+		ts, err := p.varint()
+		if err != nil {
+			return err
+		}
+
+		d.Header.OsmosisReplicationTimestamp = time.Unix(ts, 0).UTC()
+
+		return nil
+	}
+
+	var coords [4]int64
+
+	for i := range coords {
+		c, err := p.varint()
+		if err != nil {
+			return err
+		}
+
+		coords[i] = c
+	}
+
+	d.Header.BoundingBox = &model.BoundingBox{
+		Left:   model.ToDegrees(0, granularity, coords[0]),
+		Bottom: model.ToDegrees(0, granularity, coords[1]),
+		Right:  model.ToDegrees(0, granularity, coords[2]),
+		Top:    model.ToDegrees(0, granularity, coords[3]),
+	}
+
+	return nil
+}
+
+// common decodes the ID and info that start every entity.
+func (d *Decoder) common(p *payload) (model.ID, *model.Info, error) {
+	id, err := p.varint()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	id = undelta(&d.deltas.id, id)
+
+	version, err := p.uvarint()
+	if err != nil || version == 0 {
+		return model.ID(id), nil, err
+	}
+
+	info := &model.Info{Version: int32(version), Visible: true} //nolint:gosec
+
+	ts, err := p.varint()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if ts = undelta(&d.deltas.timestamp, ts); ts == 0 {
+		return model.ID(id), info, nil
+	}
+
+	info.Timestamp = time.Unix(ts, 0).UTC()
+
+	changeset, err := p.varint()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	info.Changeset = undelta(&d.deltas.changeset, changeset)
+
+	user, err := p.strings(&d.table, 2) //nolint:mnd
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if user[0] != "" {
+		uid, n := binary.Uvarint([]byte(user[0]))
+		if n <= 0 {
+			return 0, nil, fmt.Errorf("%w: bad uid", ErrInvalid)
+		}
+
+		info.UID = model.UID(uid) //nolint:gosec
+	}
+
+	info.User = user[1]
+
+	return model.ID(id), info, nil
+}
+
+// deleted marks info as not visible, since the entity holds no more than its
+// ID and info.
+func deleted(info *model.Info) *model.Info {
+	if info != nil {
+		info.Visible = false
+	}
+
+	return info
+}
+
+// tags decodes the tags that end every entity.
+func (d *Decoder) tags(p payload) (map[string]string, error) {
+	tags := map[string]string{}
+
+	for len(p) > 0 {
+		kv, err := p.strings(&d.table, 2) //nolint:mnd
+		if err != nil {
+			return nil, err
+		}
+
+		tags[kv[0]] = kv[1]
+	}
+
+	return tags, nil
+}
+
+func (d *Decoder) node(p payload) (*model.Node, error) {
+	id, info, err := d.common(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p) == 0 {
+		return &model.Node{ID: id, Tags: map[string]string{}, Info: deleted(info)}, nil
+	}
+
+	lon, err := p.varint()
+	if err != nil {
+		return nil, err
+	}
+
+	lat, err := p.varint()
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := d.tags(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Node{
+		ID:   id,
+		Tags: tags,
+		Info: info,
+		Lat:  model.ToDegrees(0, granularity, undelta(&d.deltas.lat, lat)),
+		Lon:  model.ToDegrees(0, granularity, undelta(&d.deltas.lon, lon)),
+	}, nil
+}
+
+// references splits the section of references, which is preceded by its
+// length, from the rest of the payload.
+func references(p *payload) (payload, error) {
+	n, err := p.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if n > uint64(len(*p)) {
+		return nil, fmt.Errorf("%w: truncated references", ErrInvalid)
+	}
+
+	refs := (*p)[:n]
+	*p = (*p)[n:]
+
+	return refs, nil
+}
+
+func (d *Decoder) way(p payload) (*model.Way, error) {
+	id, info, err := d.common(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p) == 0 {
+		return &model.Way{ID: id, Tags: map[string]string{}, Info: deleted(info), NodeIDs: []model.ID{}}, nil
+	}
+
+	refs, err := references(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []model.ID{}
+
+	for len(refs) > 0 {
+		ref, err := refs.varint()
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, model.ID(undelta(&d.deltas.node, ref)))
+	}
+
+	tags, err := d.tags(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Way{ID: id, Tags: tags, Info: info, NodeIDs: nodes}, nil
+}
+
+func (d *Decoder) relation(p payload) (*model.Relation, error) {
+	id, info, err := d.common(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p) == 0 {
+		return &model.Relation{ID: id, Tags: map[string]string{}, Info: deleted(info), Members: []model.Member{}}, nil
+	}
+
+	refs, err := references(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []model.Member{}
+
+	for len(refs) > 0 {
+		ref, err := refs.varint()
+		if err != nil {
+			return nil, err
+		}
+
+		role, err := refs.strings(&d.table, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if role[0] == "" || role[0][0] < '0' || role[0][0] > '2' {
+			return nil, fmt.Errorf("%w: bad member type", ErrInvalid)
+		}
+
+		typ := model.EntityType(role[0][0] - '0')
+		members = append(members, model.Member{
+			ID:   model.ID(undelta(&d.deltas.members[typ], ref)),
+			Type: typ,
+			Role: role[0][1:],
+		})
+	}
+
+	tags, err := d.tags(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Relation{ID: id, Tags: tags, Info: info, Members: members}, nil
+}
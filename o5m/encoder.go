@@ -0,0 +1,275 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package o5m
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"maps"
+	"slices"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// Encoder writes o5m, or o5c, to an output stream.
+type Encoder struct {
+	Header model.Header
+
+	w       *bufio.Writer
+	buf     []byte // the payload being written
+	refs    []byte // the references being written
+	raw     []byte // the string being written
+	typ     byte   // the dataset type of the previous entity
+	deltas  deltas
+	strings stringRefs
+}
+
+// NewEncoder returns a new encoder that writes o5m to wrtr.  The header,
+// bounding box and timestamp datasets are written from hdr straight away.
+func NewEncoder(wrtr io.Writer, hdr model.Header) (*Encoder, error) {
+	return newEncoder(wrtr, hdr, o5mHeader)
+}
+
+// NewChangeEncoder returns a new encoder that writes o5c to wrtr.  The
+// header, bounding box and timestamp datasets are written from hdr straight
+// away.
+func NewChangeEncoder(wrtr io.Writer, hdr model.Header) (*Encoder, error) {
+	return newEncoder(wrtr, hdr, o5cHeader)
+}
+
+func newEncoder(wrtr io.Writer, hdr model.Header, header string) (*Encoder, error) {
+	e := &Encoder{Header: hdr, w: bufio.NewWriter(wrtr)}
+
+	if err := e.w.WriteByte(resetDataset); err != nil {
+		return nil, err
+	}
+
+	if err := e.dataset(headerDataset, []byte(header)); err != nil {
+		return nil, err
+	}
+
+	if bbox := hdr.BoundingBox; bbox != nil {
+		p := binary.AppendVarint(nil, model.ToCoordinate(0, granularity, bbox.Left))
+		p = binary.AppendVarint(p, model.ToCoordinate(0, granularity, bbox.Bottom))
+		p = binary.AppendVarint(p, model.ToCoordinate(0, granularity, bbox.Right))
+		p = binary.AppendVarint(p, model.ToCoordinate(0, granularity, bbox.Top))
+
+		if err := e.dataset(bboxDataset, p); err != nil {
+			return nil, err
+		}
+	}
+
+	if ts := hdr.OsmosisReplicationTimestamp; !ts.IsZero() {
+		if err := e.dataset(timestampDataset, binary.AppendVarint(nil, ts.Unix())); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// Encode writes an entity.  Entities whose info is not visible are written
+// as deleted.
+func (e *Encoder) Encode(entity model.Entity) error {
+	typ := datasetTypes[model.TypeOf(entity)]
+	if typ != e.typ {
+		if err := e.reset(); err != nil {
+			return err
+		}
+
+		e.typ = typ
+	}
+
+	info := entity.GetInfo()
+
+	e.buf = binary.AppendVarint(e.buf[:0], delta(&e.deltas.id, int64(entity.GetID())))
+	e.appendInfo(info)
+
+	if info == nil || info.Visible {
+		switch entity := entity.(type) {
+		case *model.Node:
+			e.buf = binary.AppendVarint(e.buf, delta(&e.deltas.lon, model.ToCoordinate(0, granularity, entity.Lon)))
+			e.buf = binary.AppendVarint(e.buf, delta(&e.deltas.lat, model.ToCoordinate(0, granularity, entity.Lat)))
+		case *model.Way:
+			e.appendNodes(entity.NodeIDs)
+		case *model.Relation:
+			e.appendMembers(entity.Members)
+		}
+
+		e.appendTags(entity.GetTags())
+	}
+
+	return e.dataset(typ, e.buf)
+}
+
+// EncodeBatch writes an array of entities.
+func (e *Encoder) EncodeBatch(entities []model.Entity) error {
+	for _, entity := range entities {
+		if err := e.Encode(entity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EncodeChange writes the entity of a change, as deleted if it is deleted.
+func (e *Encoder) EncodeChange(c model.Change) error {
+	entity := c.Entity
+
+	if info := entity.GetInfo(); c.Action == model.DELETE && (info == nil || info.Visible) {
+		var hidden model.Info
+		if info != nil {
+			hidden = *info
+		}
+
+		hidden.Visible = false
+
+		switch v := entity.(type) {
+		case *model.Node:
+			n := *v
+			n.Info = &hidden
+			entity = &n
+		case *model.Way:
+			w := *v
+			w.Info = &hidden
+			entity = &w
+		case *model.Relation:
+			r := *v
+			r.Info = &hidden
+			entity = &r
+		}
+	}
+
+	return e.Encode(entity)
+}
+
+// Close writes the end dataset and flushes the output.
+func (e *Encoder) Close() error {
+	if err := e.w.WriteByte(endDataset); err != nil {
+		return err
+	}
+
+	return e.w.Flush()
+}
+
+// reset writes a reset dataset and clears the deltas and string table.
+func (e *Encoder) reset() error {
+	e.deltas = deltas{}
+	e.strings.reset()
+
+	return e.w.WriteByte(resetDataset)
+}
+
+// dataset writes a dataset of type typ holding the payload p.
+func (e *Encoder) dataset(typ byte, p []byte) error {
+	if err := e.w.WriteByte(typ); err != nil {
+		return err
+	}
+
+	var n [binary.MaxVarintLen64]byte
+	if _, err := e.w.Write(binary.AppendUvarint(n[:0], uint64(len(p)))); err != nil {
+		return err
+	}
+
+	_, err := e.w.Write(p)
+
+	return err
+}
+
+// appendInfo appends the version and, if it has a timestamp, the rest of the
+// info.
+func (e *Encoder) appendInfo(info *model.Info) {
+	if info == nil || info.Version <= 0 {
+		e.buf = append(e.buf, 0)
+
+		return
+	}
+
+	e.buf = binary.AppendUvarint(e.buf, uint64(info.Version))
+
+	var ts int64
+	if !info.Timestamp.IsZero() {
+		ts = info.Timestamp.Unix()
+	}
+
+	e.buf = binary.AppendVarint(e.buf, delta(&e.deltas.timestamp, ts))
+	if ts == 0 {
+		return
+	}
+
+	e.buf = binary.AppendVarint(e.buf, delta(&e.deltas.changeset, info.Changeset))
+
+	e.raw = e.raw[:0]
+	if info.UID != 0 {
+		e.raw = binary.AppendUvarint(e.raw, uint64(info.UID)) //nolint:gosec
+	}
+
+	e.raw = append(e.raw, 0)
+	e.raw = append(e.raw, info.User...)
+	e.raw = append(e.raw, 0)
+	e.buf = e.appendString(e.buf, e.raw)
+}
+
+// appendNodes appends the references of a way.
+func (e *Encoder) appendNodes(ids []model.ID) {
+	e.refs = e.refs[:0]
+	for _, id := range ids {
+		e.refs = binary.AppendVarint(e.refs, delta(&e.deltas.node, int64(id)))
+	}
+
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(e.refs)))
+	e.buf = append(e.buf, e.refs...)
+}
+
+// appendMembers appends the references of a relation.
+func (e *Encoder) appendMembers(members []model.Member) {
+	e.refs = e.refs[:0]
+	for _, m := range members {
+		e.refs = binary.AppendVarint(e.refs, delta(&e.deltas.members[m.Type], int64(m.ID)))
+
+		e.raw = append(e.raw[:0], '0'+byte(m.Type))
+		e.raw = append(e.raw, m.Role...)
+		e.raw = append(e.raw, 0)
+		e.refs = e.appendString(e.refs, e.raw)
+	}
+
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(e.refs)))
+	e.buf = append(e.buf, e.refs...)
+}
+
+// appendTags appends the tags, sorted by key.
+func (e *Encoder) appendTags(tags map[string]string) {
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		e.raw = append(e.raw[:0], k...)
+		e.raw = append(e.raw, 0)
+		e.raw = append(e.raw, tags[k]...)
+		e.raw = append(e.raw, 0)
+		e.buf = e.appendString(e.buf, e.raw)
+	}
+}
+
+// appendString appends raw, zero terminated strings, to b as a reference to
+// the table if it holds them, else inline.
+func (e *Encoder) appendString(b, raw []byte) []byte {
+	if ref, ok := e.strings.ref(raw); ok {
+		return binary.AppendUvarint(b, ref)
+	}
+
+	e.strings.add(raw)
+
+	return append(append(b, 0), raw...)
+}
@@ -0,0 +1,254 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package o5m decodes and encodes the o5m format, and its o5c variant for
+changes, producing and consuming the same model values as the PBF Decoder and
+Encoder.
+
+An o5m file is a sequence of datasets: a header, an optional bounding box and
+timestamp, then nodes, ways and relations.  Entities are delta coded against
+the previous entity and strings, tags, users and member roles, are written
+once and then referenced by their position in a table of recently written
+strings.  A reset dataset clears the deltas and the table; the encoder writes
+one before each run of entities of a type.
+
+Deleted entities hold only their ID and info.  They are decoded, and encoded,
+as entities whose info is not visible.
+*/
+package o5m
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// ErrInvalid is returned when the input is not valid o5m.
+var ErrInvalid = errors.New("invalid o5m")
+
+// Dataset types.
+const (
+	nodeDataset      = 0x10
+	wayDataset       = 0x11
+	relationDataset  = 0x12
+	bboxDataset      = 0xdb
+	timestampDataset = 0xdc
+	headerDataset    = 0xe0
+	endDataset       = 0xfe
+	resetDataset     = 0xff
+
+	// datasets from singleDatasets onwards have no length or payload
+	singleDatasets = 0xf0
+)
+
+// Header dataset payloads.
+const (
+	o5mHeader = "o5m2"
+	o5cHeader = "o5c2"
+)
+
+const (
+	// tableSize is the number of strings the string table holds.
+	tableSize = 15000
+
+	// maxTableEntry is the length of the longest string, including its
+	// terminating zeros, held by the string table.
+	maxTableEntry = 252
+
+	// granularity is the size, in nanodegrees, of coordinate units.
+	granularity = 100
+)
+
+// datasetTypes maps entity types to the datasets that hold them.
+var datasetTypes = map[model.EntityType]byte{
+	model.NODE:     nodeDataset,
+	model.WAY:      wayDataset,
+	model.RELATION: relationDataset,
+}
+
+// deltas holds the previous values that fields are delta coded against.
+type deltas struct {
+	id, timestamp, changeset int64
+	lon, lat                 int64
+	node                     int64    // the node references of ways
+	members                  [3]int64 // the member references, by type
+}
+
+// delta returns v less the previous value, which becomes v.
+func delta(prev *int64, v int64) int64 {
+	d := v - *prev
+	*prev = v
+
+	return d
+}
+
+// undelta returns d added to the previous value, which becomes the sum.
+func undelta(prev *int64, d int64) int64 {
+	*prev += d
+
+	return *prev
+}
+
+// payload reads the fields of a dataset.
+type payload []byte
+
+func (p *payload) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(*p)
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: bad number", ErrInvalid)
+	}
+
+	*p = (*p)[n:]
+
+	return v, nil
+}
+
+func (p *payload) varint() (int64, error) {
+	v, n := binary.Varint(*p)
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: bad number", ErrInvalid)
+	}
+
+	*p = (*p)[n:]
+
+	return v, nil
+}
+
+// strings reads n zero terminated strings, written inline or referencing the
+// table.  Inline strings are added to the table.
+func (p *payload) strings(t *stringTable, n int) ([]string, error) {
+	var raw []byte
+
+	if len(*p) > 0 && (*p)[0] == 0 {
+		rest := (*p)[1:]
+		end := 0
+
+		for range n {
+			i := bytes.IndexByte(rest[end:], 0)
+			if i < 0 {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalid)
+			}
+
+			end += i + 1
+		}
+
+		raw = rest[:end]
+		*p = rest[end:]
+
+		t.add(raw)
+	} else {
+		ref, err := p.uvarint()
+		if err != nil {
+			return nil, err
+		}
+
+		if raw, err = t.get(ref); err != nil {
+			return nil, err
+		}
+	}
+
+	ss := bytes.SplitN(raw, []byte{0}, n+1)
+	if len(ss) != n+1 {
+		return nil, fmt.Errorf("%w: expected %d strings", ErrInvalid, n)
+	}
+
+	out := make([]string, n)
+	for i := range n {
+		out[i] = string(ss[i])
+	}
+
+	return out, nil
+}
+
+// stringTable holds the strings most recently written inline, for decoding.
+type stringTable struct {
+	entries [][]byte
+	next    int // the index of the next entry to be written
+}
+
+func (t *stringTable) add(raw []byte) {
+	if len(raw) > maxTableEntry {
+		return
+	}
+
+	raw = bytes.Clone(raw)
+
+	if len(t.entries) < tableSize {
+		t.entries = append(t.entries, raw)
+	} else {
+		t.entries[t.next] = raw
+	}
+
+	t.next = (t.next + 1) % tableSize
+}
+
+// get returns the entry ref strings ago, one being the most recent.
+func (t *stringTable) get(ref uint64) ([]byte, error) {
+	if ref == 0 || ref > uint64(len(t.entries)) {
+		return nil, fmt.Errorf("%w: bad string reference %d", ErrInvalid, ref)
+	}
+
+	return t.entries[(t.next-int(ref)+tableSize)%tableSize], nil
+}
+
+func (t *stringTable) reset() {
+	t.entries = t.entries[:0]
+	t.next = 0
+}
+
+// stringRefs holds the strings most recently written inline, for encoding.
+type stringRefs struct {
+	seqs  map[string]int    // the sequence number at which strings were added
+	keys  [tableSize]string // the strings, by sequence number modulo tableSize
+	count int               // the number of strings added
+}
+
+// ref returns the reference to raw, if it is held by the table.
+func (r *stringRefs) ref(raw []byte) (uint64, bool) {
+	seq, ok := r.seqs[string(raw)]
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(r.count - seq), true
+}
+
+func (r *stringRefs) add(raw []byte) {
+	if len(raw) > maxTableEntry {
+		return
+	}
+
+	if r.seqs == nil {
+		r.seqs = make(map[string]int)
+	}
+
+	// the string pushed out of the table can no longer be referenced
+	slot := r.count % tableSize
+	if old := r.keys[slot]; r.count >= tableSize && r.seqs[old] == r.count-tableSize {
+		delete(r.seqs, old)
+	}
+
+	r.keys[slot] = string(raw)
+	r.seqs[r.keys[slot]] = r.count
+	r.count++
+}
+
+func (r *stringRefs) reset() {
+	clear(r.seqs)
+	r.count = 0
+}
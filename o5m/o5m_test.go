@@ -0,0 +1,218 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package o5m_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/o5m"
+)
+
+func encode(t *testing.T, hdr model.Header, entities []model.Entity) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	e, err := o5m.NewEncoder(&buf, hdr)
+	require.NoError(t, err)
+	require.NoError(t, e.EncodeBatch(entities))
+	require.NoError(t, e.Close())
+
+	return buf.Bytes()
+}
+
+func decode(t *testing.T, in []byte) (*o5m.Decoder, []model.Entity) {
+	t.Helper()
+
+	d, err := o5m.NewDecoder(bytes.NewReader(in))
+	require.NoError(t, err)
+
+	var entities []model.Entity
+
+	for e, err := range d.All() {
+		require.NoError(t, err)
+
+		entities = append(entities, e)
+	}
+
+	return d, entities
+}
+
+func TestEncode(t *testing.T) {
+	info := &model.Info{Version: 1, UID: 2, Timestamp: time.Unix(10, 0), Changeset: 3, User: "u", Visible: true}
+
+	in := encode(t, model.Header{}, []model.Entity{
+		&model.Node{ID: 5, Tags: map[string]string{"a": "b"}, Info: info, Lat: 0.0000002, Lon: -0.0000001},
+		&model.Node{ID: 6, Tags: map[string]string{"a": "b"}, Lat: 0.0000002, Lon: -0.0000001},
+	})
+
+	assert.Equal(t, []byte{
+		0xff,                           // reset
+		0xe0, 0x04, 'o', '5', 'm', '2', // header
+		0xff,       // reset before the nodes
+		0x10, 0x10, // node, 16 bytes
+		0x0a,             // id 5
+		0x01, 0x14, 0x06, // version 1, timestamp 10, changeset 3
+		0x00, 0x02, 0x00, 'u', 0x00, // uid 2 and user u
+		0x01, 0x04, // lon -1, lat 2
+		0x00, 'a', 0x00, 'b', 0x00, // tag a=b
+		0x10, 0x05, // node, 5 bytes
+		0x02,       // id 6
+		0x00,       // no info
+		0x00, 0x00, // lon -1, lat 2, unchanged
+		0x01, // tag a=b, the most recent string
+		0xfe, // end
+	}, in)
+}
+
+func TestRoundTrip(t *testing.T) {
+	f, err := os.Open("../testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer f.Close()
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+
+	d, err := pbf.NewDecoderAt(context.Background(), f, fi.Size())
+	require.NoError(t, err)
+	defer d.Close()
+
+	var want []model.Entity
+
+	for e, err := range d.All() {
+		require.NoError(t, err)
+
+		want = append(want, e)
+	}
+
+	hdr := model.Header{
+		BoundingBox:                 &model.BoundingBox{Left: -0.25, Bottom: 51.76, Right: -0.21, Top: 51.78},
+		OsmosisReplicationTimestamp: time.Date(2024, 10, 28, 21, 21, 30, 0, time.UTC),
+	}
+
+	od, got := decode(t, encode(t, hdr, want))
+	assert.Equal(t, hdr, od.Header)
+	assert.False(t, od.Change)
+
+	require.Len(t, got, len(want))
+
+	for i := range want {
+		assert.True(t, model.Equal(want[i], got[i]), "%v != %v", want[i], got[i])
+	}
+}
+
+func TestStringTableEviction(t *testing.T) {
+	var want []model.Entity
+
+	// the first strings are pushed out of the table, and so written again
+	for i := range 15050 {
+		want = append(want, &model.Node{ID: model.ID(i), Tags: map[string]string{"k": fmt.Sprint(i % 15010)}})
+	}
+
+	_, got := decode(t, encode(t, model.Header{}, want))
+	require.Len(t, got, len(want))
+
+	for i := range want {
+		assert.Equal(t, want[i].GetTags(), got[i].GetTags())
+	}
+}
+
+func TestChanges(t *testing.T) {
+	ts := time.Unix(1700000000, 0).UTC()
+
+	changes := []model.Change{
+		{Action: model.CREATE, Entity: &model.Node{
+			ID: 1, Tags: map[string]string{}, Info: &model.Info{Version: 1, Timestamp: ts, Visible: true}, Lat: 1, Lon: 2,
+		}},
+		{Action: model.MODIFY, Entity: &model.Way{
+			ID: 2, Tags: map[string]string{"highway": "path"}, NodeIDs: []model.ID{1, 3},
+			Info: &model.Info{Version: 4, Timestamp: ts, Changeset: 9, UID: 300, User: "Kjc", Visible: true},
+		}},
+		{Action: model.DELETE, Entity: &model.Relation{
+			ID: 3, Tags: map[string]string{"type": "route"}, Info: &model.Info{Version: 2, Timestamp: ts, Visible: true},
+			Members: []model.Member{{ID: 2, Type: model.WAY, Role: "forward"}},
+		}},
+	}
+
+	var buf bytes.Buffer
+
+	e, err := o5m.NewChangeEncoder(&buf, model.Header{})
+	require.NoError(t, err)
+
+	for _, c := range changes {
+		require.NoError(t, e.EncodeChange(c))
+	}
+
+	require.NoError(t, e.Close())
+
+	d, err := o5m.NewDecoder(&buf)
+	require.NoError(t, err)
+	assert.True(t, d.Change)
+
+	var got []model.Change
+
+	for c, err := range d.Changes() {
+		require.NoError(t, err)
+
+		got = append(got, c)
+	}
+
+	require.Len(t, got, len(changes))
+
+	for i := range changes {
+		assert.Equal(t, changes[i].Action, got[i].Action)
+	}
+
+	assert.True(t, model.Equal(changes[0].Entity, got[0].Entity))
+	assert.True(t, model.Equal(changes[1].Entity, got[1].Entity))
+	assert.Equal(t, &model.Relation{
+		ID:      3,
+		Tags:    map[string]string{},
+		Info:    &model.Info{Version: 2, Timestamp: ts},
+		Members: []model.Member{},
+	}, got[2].Entity)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for name, in := range map[string][]byte{
+		"empty":          {},
+		"no reset":       {0xe0, 0x04, 'o', '5', 'm', '2'},
+		"no header":      {0xff, 0x10, 0x00},
+		"unknown header": {0xff, 0xe0, 0x04, 'o', '5', 'x', '2'},
+		"truncated":      {0xff, 0xe0, 0x04, 'o', '5', 'm', '2', 0x10, 0x09, 0x02},
+		"bad reference":  {0xff, 0xe0, 0x04, 'o', '5', 'm', '2', 0x10, 0x04, 0x02, 0x00, 0x00, 0x00, 0x10, 0x05, 0x02, 0x00, 0x00, 0x00, 0x07},
+		"bad string":     {0xff, 0xe0, 0x04, 'o', '5', 'm', '2', 0x10, 0x06, 0x02, 0x00, 0x00, 0x00, 0x00, 'a'},
+		"bad member":     {0xff, 0xe0, 0x04, 'o', '5', 'm', '2', 0x12, 0x07, 0x02, 0x00, 0x04, 0x02, 0x00, '7', 0x00},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d, err := o5m.NewDecoder(bytes.NewReader(in))
+			if err == nil {
+				_, err = d.Decode()
+			}
+
+			assert.ErrorIs(t, err, o5m.ErrInvalid)
+		})
+	}
+}
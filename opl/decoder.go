@@ -0,0 +1,351 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+
+	"m4o.io/pbf/v2/model"
+)
+
+const (
+	// BatchSize is the max number of entities returned by Decoder.Decode.
+	BatchSize = 8000
+
+	// maxLineSize is the length of the longest line that can be decoded.
+	maxLineSize = 64 << 20
+)
+
+// Decoder reads OPL from an input stream.
+type Decoder struct {
+	s    *bufio.Scanner
+	line int // the number of the line last read
+}
+
+// NewDecoder returns a new decoder that reads from rdr.
+func NewDecoder(rdr io.Reader) *Decoder {
+	s := bufio.NewScanner(rdr)
+	s.Buffer(nil, maxLineSize)
+
+	return &Decoder{s: s}
+}
+
+// Decode reads the entities of the next batch of at most BatchSize lines, in
+// input order.  Empty lines are skipped.  The end of the input is reported by
+// an io.EOF error.
+func (d *Decoder) Decode() ([]model.Entity, error) {
+	var entities []model.Entity
+
+	for len(entities) < BatchSize && d.s.Scan() {
+		d.line++
+
+		line := d.s.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		e, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", d.line, err)
+		}
+
+		entities = append(entities, e)
+	}
+
+	if err := d.s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(entities) == 0 {
+		return nil, io.EOF
+	}
+
+	return entities, nil
+}
+
+// All returns an iterator over the decoded entities.  Iteration ends at the
+// end of the input or once an error has been yielded.
+func (d *Decoder) All() iter.Seq2[model.Entity, error] {
+	return func(yield func(model.Entity, error) bool) {
+		for {
+			entities, err := d.Decode()
+			if errors.Is(err, io.EOF) {
+				return
+			} else if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			for _, e := range entities {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// entity holds the fields of a line as they are parsed.
+type entity struct {
+	typ      model.EntityType
+	id       model.ID
+	info     *model.Info
+	tags     map[string]string
+	lat, lon model.Degrees
+	way      model.Way
+	members  []model.Member
+}
+
+// parseLine parses the entity of a line.
+func parseLine(line string) (model.Entity, error) {
+	fields := strings.Fields(line)
+
+	typ, ok := types[fields[0][0]]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown entity type %q", ErrInvalid, fields[0][:1])
+	}
+
+	id, err := strconv.ParseInt(fields[0][1:], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	e := &entity{typ: typ, id: model.ID(id), tags: map[string]string{}}
+
+	for _, f := range fields[1:] {
+		if err := e.parseField(f[0], f[1:]); err != nil {
+			return nil, err
+		}
+	}
+
+	switch typ {
+	case model.NODE:
+		return &model.Node{ID: e.id, Tags: e.tags, Info: e.info, Lat: e.lat, Lon: e.lon}, nil
+	case model.WAY:
+		w := e.way
+		w.ID, w.Tags, w.Info = e.id, e.tags, e.info
+
+		if w.NodeIDs == nil {
+			w.NodeIDs = []model.ID{}
+		}
+
+		return &w, nil
+	default:
+		if e.members == nil {
+			e.members = []model.Member{}
+		}
+
+		return &model.Relation{ID: e.id, Tags: e.tags, Info: e.info, Members: e.members}, nil
+	}
+}
+
+// parseField parses the field identified by key.
+func (e *entity) parseField(key byte, v string) error {
+	var err error
+
+	switch key {
+	case 'v', 'd', 'c', 't', 'i', 'u':
+		err = e.parseInfo(key, v)
+	case 'T':
+		err = e.parseTags(v)
+	case 'x':
+		e.lon, err = parseDegrees(v)
+	case 'y':
+		e.lat, err = parseDegrees(v)
+	case 'N':
+		err = e.parseNodes(v)
+	case 'M':
+		err = e.parseMembers(v)
+	default:
+		err = fmt.Errorf("%w: unknown field %q", ErrInvalid, key)
+	}
+
+	return err
+}
+
+// parseInfo parses one of the fields of the info, which is created by the
+// first of them.
+func (e *entity) parseInfo(key byte, v string) error {
+	if e.info == nil {
+		e.info = &model.Info{Visible: true}
+	}
+
+	var err error
+
+	switch key {
+	case 'v':
+		var n int64
+		n, err = strconv.ParseInt(v, 10, 32)
+		e.info.Version = int32(n)
+	case 'd':
+		if v != "V" && v != "D" {
+			return fmt.Errorf("%w: unknown visibility %q", ErrInvalid, v)
+		}
+
+		e.info.Visible = v == "V"
+	case 'c':
+		e.info.Changeset, err = strconv.ParseInt(v, 10, 64)
+	case 't':
+		if v != "" {
+			e.info.Timestamp, err = time.Parse(time.RFC3339, v)
+		}
+	case 'i':
+		var n int64
+		n, err = strconv.ParseInt(v, 10, 32)
+		e.info.UID = model.UID(n)
+	case 'u':
+		e.info.User, err = unescape(v)
+	}
+
+	if err != nil && !errors.Is(err, ErrInvalid) {
+		err = fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	return err
+}
+
+func (e *entity) parseTags(v string) error {
+	if v == "" {
+		return nil
+	}
+
+	for _, kv := range strings.Split(v, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("%w: tag %q has no value", ErrInvalid, kv)
+		}
+
+		k, err := unescape(k)
+		if err != nil {
+			return err
+		}
+
+		if e.tags[k], err = unescape(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseNodes parses the nodes of a way, with their locations if every node
+// has one.
+func (e *entity) parseNodes(v string) error {
+	e.way.NodeIDs = []model.ID{}
+	if v == "" {
+		return nil
+	}
+
+	refs := strings.Split(v, ",")
+	lats := make([]model.Degrees, 0, len(refs))
+	lons := make([]model.Degrees, 0, len(refs))
+
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref, "n") {
+			return fmt.Errorf("%w: bad node reference %q", ErrInvalid, ref)
+		}
+
+		ref, loc, located := strings.Cut(ref[1:], "x")
+
+		id, err := strconv.ParseInt(ref, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+
+		e.way.NodeIDs = append(e.way.NodeIDs, model.ID(id))
+
+		if !located {
+			continue
+		}
+
+		x, y, ok := strings.Cut(loc, "y")
+		if !ok {
+			return fmt.Errorf("%w: bad node location %q", ErrInvalid, loc)
+		}
+
+		lon, err := parseDegrees(x)
+		if err != nil {
+			return err
+		}
+
+		lat, err := parseDegrees(y)
+		if err != nil {
+			return err
+		}
+
+		lats, lons = append(lats, lat), append(lons, lon)
+	}
+
+	if len(lats) == len(refs) {
+		e.way.Lats, e.way.Lons = lats, lons
+	}
+
+	return nil
+}
+
+func (e *entity) parseMembers(v string) error {
+	e.members = []model.Member{}
+	if v == "" {
+		return nil
+	}
+
+	for _, m := range strings.Split(v, ",") {
+		ref, role, ok := strings.Cut(m, "@")
+		if !ok || ref == "" {
+			return fmt.Errorf("%w: bad member %q", ErrInvalid, m)
+		}
+
+		typ, ok := types[ref[0]]
+		if !ok {
+			return fmt.Errorf("%w: unknown member type %q", ErrInvalid, ref[:1])
+		}
+
+		id, err := strconv.ParseInt(ref[1:], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+
+		if role, err = unescape(role); err != nil {
+			return err
+		}
+
+		e.members = append(e.members, model.Member{ID: model.ID(id), Type: typ, Role: role})
+	}
+
+	return nil
+}
+
+// parseDegrees parses a coordinate; an empty one, of an entity without a
+// location, is zero.
+func parseDegrees(s string) (model.Degrees, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	d, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	return model.Degrees(d), nil
+}
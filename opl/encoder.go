@@ -0,0 +1,172 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opl
+
+import (
+	"bufio"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// Encoder writes OPL to an output stream.
+type Encoder struct {
+	w   *bufio.Writer
+	buf []byte // the line being written
+}
+
+// NewEncoder returns a new encoder that writes to wrtr.
+func NewEncoder(wrtr io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(wrtr)}
+}
+
+// Encode writes the line of an entity.
+func (e *Encoder) Encode(entity model.Entity) error {
+	b := append(e.buf[:0], typeNames[model.TypeOf(entity)])
+	b = strconv.AppendInt(b, int64(entity.GetID()), 10)
+	b = appendInfo(b, entity.GetInfo())
+	b = appendTags(b, entity.GetTags())
+
+	switch entity := entity.(type) {
+	case *model.Node:
+		b = append(b, " x"...)
+		b = formatDegrees(b, entity.Lon)
+		b = append(b, " y"...)
+		b = formatDegrees(b, entity.Lat)
+	case *model.Way:
+		b = appendNodes(b, entity)
+	case *model.Relation:
+		b = appendMembers(b, entity.Members)
+	}
+
+	b = append(b, '\n')
+	e.buf = b
+
+	_, err := e.w.Write(b)
+
+	return err
+}
+
+// EncodeBatch writes the lines of an array of entities.
+func (e *Encoder) EncodeBatch(entities []model.Entity) error {
+	for _, entity := range entities {
+		if err := e.Encode(entity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close flushes the output.
+func (e *Encoder) Close() error {
+	return e.w.Flush()
+}
+
+// appendInfo appends the version, visibility, changeset, timestamp, uid and
+// user fields, unless info is nil.
+func appendInfo(b []byte, info *model.Info) []byte {
+	if info == nil {
+		return b
+	}
+
+	b = append(b, " v"...)
+	b = strconv.AppendInt(b, int64(info.Version), 10)
+
+	if info.Visible {
+		b = append(b, " dV"...)
+	} else {
+		b = append(b, " dD"...)
+	}
+
+	b = append(b, " c"...)
+	b = strconv.AppendInt(b, info.Changeset, 10)
+	b = append(b, " t"...)
+
+	if !info.Timestamp.IsZero() {
+		b = info.Timestamp.UTC().AppendFormat(b, time.RFC3339)
+	}
+
+	b = append(b, " i"...)
+	b = strconv.AppendInt(b, int64(info.UID), 10)
+	b = append(b, " u"...)
+
+	return escape(b, info.User)
+}
+
+// appendTags appends the tags field, sorted by key.
+func appendTags(b []byte, tags map[string]string) []byte {
+	b = append(b, " T"...)
+
+	for i, k := range slices.Sorted(maps.Keys(tags)) {
+		if i > 0 {
+			b = append(b, ',')
+		}
+
+		b = escape(b, k)
+		b = append(b, '=')
+		b = escape(b, tags[k])
+	}
+
+	return b
+}
+
+// appendNodes appends the nodes field of w, with their locations if w has
+// them.
+func appendNodes(b []byte, w *model.Way) []byte {
+	located := len(w.Lats) == len(w.NodeIDs) && len(w.Lons) == len(w.NodeIDs)
+
+	b = append(b, " N"...)
+
+	for i, id := range w.NodeIDs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+
+		b = append(b, 'n')
+		b = strconv.AppendInt(b, int64(id), 10)
+
+		if located {
+			b = append(b, 'x')
+			b = formatDegrees(b, w.Lons[i])
+			b = append(b, 'y')
+			b = formatDegrees(b, w.Lats[i])
+		}
+	}
+
+	return b
+}
+
+// appendMembers appends the members field.
+func appendMembers(b []byte, members []model.Member) []byte {
+	b = append(b, " M"...)
+
+	for i, m := range members {
+		if i > 0 {
+			b = append(b, ',')
+		}
+
+		b = append(b, typeNames[m.Type])
+		b = strconv.AppendInt(b, int64(m.ID), 10)
+		b = append(b, '@')
+		b = escape(b, m.Role)
+	}
+
+	return b
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package opl decodes and encodes the OPL (Object Per Line) format of osmium,
+producing and consuming the same model values as the PBF Decoder and Encoder.
+
+Each line holds one entity as fields separated by spaces: the type and ID,
+then the version (v), visibility (d), changeset (c), timestamp (t), uid (i)
+and user (u), the tags (T) and either the location (x and y) of a node, the
+nodes (N) of a way or the members (M) of a relation.  Characters that would
+be ambiguous within a field are escaped as their hexadecimal code point
+between percent signs, so a space is written as %20%.
+*/
+package opl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// ErrInvalid is returned when the input is not valid OPL.
+var ErrInvalid = errors.New("invalid OPL")
+
+// typeNames maps entity types to the characters that start their lines and
+// identify members.
+var typeNames = map[model.EntityType]byte{
+	model.NODE:     'n',
+	model.WAY:      'w',
+	model.RELATION: 'r',
+}
+
+// types maps the characters that start lines and identify members to entity
+// types.
+var types = map[byte]model.EntityType{
+	'n': model.NODE,
+	'w': model.WAY,
+	'r': model.RELATION,
+}
+
+// isPlain reports whether r is written as is, rather than escaped, following
+// osmium.
+func isPlain(r rune) bool {
+	return (r >= 0x21 && r <= 0x24) || (r >= 0x26 && r <= 0x2b) || (r >= 0x2d && r <= 0x3c) ||
+		(r >= 0x3e && r <= 0x3f) || (r >= 0x41 && r <= 0x7e) || (r >= 0xa1 && r <= 0xac) ||
+		(r >= 0xae && r <= 0x05ff)
+}
+
+// escape appends s to b, escaping the characters that are not plain.
+func escape(b []byte, s string) []byte {
+	for _, r := range s {
+		if isPlain(r) {
+			b = utf8.AppendRune(b, r)
+		} else {
+			b = append(b, '%')
+			b = strconv.AppendInt(b, int64(r), 16)
+			b = append(b, '%')
+		}
+	}
+
+	return b
+}
+
+// unescape returns s with its escaped characters replaced.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, "%") {
+		return s, nil
+	}
+
+	var b strings.Builder
+
+	for {
+		i := strings.IndexByte(s, '%')
+		if i < 0 {
+			b.WriteString(s)
+
+			return b.String(), nil
+		}
+
+		b.WriteString(s[:i])
+		s = s[i+1:]
+
+		j := strings.IndexByte(s, '%')
+		if j < 0 {
+			return "", fmt.Errorf("%w: unterminated escape in %q", ErrInvalid, s)
+		}
+
+		r, err := strconv.ParseUint(s[:j], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return "", fmt.Errorf("%w: bad escape %q", ErrInvalid, s[:j])
+		}
+
+		b.WriteRune(rune(r))
+		s = s[j+1:]
+	}
+}
+
+// formatDegrees appends the shortest representation of d to b.
+func formatDegrees(b []byte, d model.Degrees) []byte {
+	return strconv.AppendFloat(b, float64(d), 'f', -1, 64)
+}
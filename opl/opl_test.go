@@ -0,0 +1,171 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opl_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/opl"
+)
+
+func TestEncode(t *testing.T) {
+	ts := time.Date(2012, 4, 9, 21, 37, 39, 0, time.UTC)
+
+	var buf bytes.Buffer
+
+	e := opl.NewEncoder(&buf)
+	require.NoError(t, e.EncodeBatch([]model.Entity{
+		&model.Node{
+			ID:   1,
+			Tags: map[string]string{"name": "Café, 100% good", "amenity": "bench"},
+			Info: &model.Info{Version: 2, UID: 3, Timestamp: ts, Changeset: 7, User: "Kjc co", Visible: true},
+			Lat:  51.5,
+			Lon:  -0.1,
+		},
+		&model.Way{
+			ID:      3,
+			Info:    &model.Info{Version: 1},
+			NodeIDs: []model.ID{1, 2},
+			Lats:    []model.Degrees{51.5, 51.6},
+			Lons:    []model.Degrees{-0.1, -0.2},
+		},
+		&model.Relation{
+			ID:      4,
+			Tags:    map[string]string{"type": "multipolygon"},
+			Members: []model.Member{{ID: 3, Type: model.WAY, Role: "outer"}, {ID: 1, Type: model.NODE}},
+		},
+	}))
+	require.NoError(t, e.Close())
+
+	assert.Equal(t, `n1 v2 dV c7 t2012-04-09T21:37:39Z i3 uKjc%20%co Tamenity=bench,name=Café%2c%%20%100%25%%20%good x-0.1 y51.5
+w3 v1 dD c0 t i0 u T Nn1x-0.1y51.5,n2x-0.2y51.6
+r4 Ttype=multipolygon Mw3@outer,n1@
+`, buf.String())
+}
+
+func TestDecode(t *testing.T) {
+	in := `n1 v2 dV c7 t2012-04-09T21:37:39Z i3 uKjc%20%co Tamenity=bench,name=Caf%e9%%2c% x-0.1 y51.5
+
+w3 v1 dD c0 t i0 u T Nn1,n2
+r4 Ttype=multipolygon Mw3@outer,n1@
+`
+
+	var entities []model.Entity
+
+	for e, err := range opl.NewDecoder(strings.NewReader(in)).All() {
+		require.NoError(t, err)
+
+		entities = append(entities, e)
+	}
+
+	assert.Equal(t, []model.Entity{
+		&model.Node{
+			ID:   1,
+			Tags: map[string]string{"name": "Café,", "amenity": "bench"},
+			Info: &model.Info{
+				Version:   2,
+				UID:       3,
+				Timestamp: time.Date(2012, 4, 9, 21, 37, 39, 0, time.UTC),
+				Changeset: 7,
+				User:      "Kjc co",
+				Visible:   true,
+			},
+			Lat: 51.5,
+			Lon: -0.1,
+		},
+		&model.Way{ID: 3, Tags: map[string]string{}, Info: &model.Info{Version: 1}, NodeIDs: []model.ID{1, 2}},
+		&model.Relation{
+			ID:      4,
+			Tags:    map[string]string{"type": "multipolygon"},
+			Members: []model.Member{{ID: 3, Type: model.WAY, Role: "outer"}, {ID: 1, Type: model.NODE}},
+		},
+	}, entities)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, line := range []string{
+		"a1",
+		"nfoo",
+		"n1 v1 q2",
+		"n1 dX",
+		"n1 tyesterday",
+		"n1 xeast",
+		"n1 Tfoo",
+		"n1 Tfoo=%zz%",
+		"n1 Tfoo=%20",
+		"w1 N1,2",
+		"w1 Nn1x2",
+		"r1 Mn1",
+		"r1 Mq1@role",
+	} {
+		t.Run(line, func(t *testing.T) {
+			_, err := opl.NewDecoder(strings.NewReader(line)).Decode()
+			assert.ErrorIs(t, err, opl.ErrInvalid)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	f, err := os.Open("../testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer f.Close()
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+
+	d, err := pbf.NewDecoderAt(context.Background(), f, fi.Size())
+	require.NoError(t, err)
+	defer d.Close()
+
+	var (
+		want []model.Entity
+		buf  bytes.Buffer
+	)
+
+	e := opl.NewEncoder(&buf)
+
+	for entity, err := range d.All() {
+		require.NoError(t, err)
+		require.NoError(t, e.Encode(entity))
+
+		want = append(want, entity)
+	}
+
+	require.NoError(t, e.Close())
+
+	var got []model.Entity
+
+	for entity, err := range opl.NewDecoder(&buf).All() {
+		require.NoError(t, err)
+
+		got = append(got, entity)
+	}
+
+	require.Len(t, got, len(want))
+
+	for i := range want {
+		assert.True(t, model.Equal(want[i], got[i]), "%v != %v", want[i], got[i])
+	}
+}
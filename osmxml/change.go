@@ -0,0 +1,280 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osmxml
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// actions maps the action elements of OsmChange to change actions.
+var actions = map[string]model.Action{
+	"create": model.CREATE,
+	"modify": model.MODIFY,
+	"delete": model.DELETE,
+}
+
+// actionNames maps change actions to the action elements of OsmChange.
+var actionNames = map[model.Action]string{
+	model.CREATE: "create",
+	model.MODIFY: "modify",
+	model.DELETE: "delete",
+}
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ChangeDecoder reads OpenStreetMap change files, .osc, from an input stream.
+type ChangeDecoder struct {
+	// Generator is the generator of the osmChange element.
+	Generator string
+
+	dec    *xml.Decoder
+	action *model.Action // the action element being read, if any
+}
+
+// NewChangeDecoder returns a new decoder that reads from rdr, which may be
+// gzip compressed as .osc.gz files are.  The decoder is initialized with the
+// osmChange element.
+func NewChangeDecoder(rdr io.Reader) (*ChangeDecoder, error) {
+	br := bufio.NewReader(rdr)
+
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+
+		rdr = zr
+	} else {
+		rdr = br
+	}
+
+	d := &ChangeDecoder{dec: xml.NewDecoder(rdr)}
+
+	var (
+		se xml.StartElement
+		ok bool
+	)
+
+	for !ok {
+		tok, err := d.token()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: missing osmChange element", ErrInvalid)
+		} else if err != nil {
+			return nil, err
+		}
+
+		se, ok = tok.(xml.StartElement)
+	}
+
+	if se.Name.Local != "osmChange" {
+		return nil, fmt.Errorf("%w: unexpected %s element", ErrInvalid, se.Name.Local)
+	}
+
+	for _, attr := range se.Attr {
+		switch attr.Name.Local {
+		case "version":
+			if attr.Value != version {
+				return nil, fmt.Errorf("%w: unsupported version %s", ErrInvalid, attr.Value)
+			}
+		case "generator":
+			d.Generator = attr.Value
+		}
+	}
+
+	return d, nil
+}
+
+// Decode reads the next batch of at most BatchSize changes, in input order.
+// The end of the input is reported by an io.EOF error.
+func (d *ChangeDecoder) Decode() ([]model.Change, error) {
+	var changes []model.Change
+
+	for len(changes) < BatchSize {
+		tok, err := d.token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			c, err := d.decodeChange(tok)
+			if err != nil {
+				return nil, err
+			}
+
+			if c != nil {
+				changes = append(changes, *c)
+			}
+		case xml.EndElement:
+			if _, ok := actions[tok.Name.Local]; ok {
+				d.action = nil
+			}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, io.EOF
+	}
+
+	return changes, nil
+}
+
+// All returns an iterator over the decoded changes.  Iteration ends at the
+// end of the input or once an error has been yielded.
+func (d *ChangeDecoder) All() iter.Seq2[model.Change, error] {
+	return func(yield func(model.Change, error) bool) {
+		for {
+			changes, err := d.Decode()
+			if errors.Is(err, io.EOF) {
+				return
+			} else if err != nil {
+				yield(model.Change{}, err)
+
+				return
+			}
+
+			for _, c := range changes {
+				if !yield(c, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// decodeChange decodes the change started by se.  An action element starts
+// the changes that follow, and other elements are skipped; both yield nil.
+func (d *ChangeDecoder) decodeChange(se xml.StartElement) (*model.Change, error) {
+	if a, ok := actions[se.Name.Local]; ok {
+		d.action = &a
+
+		return nil, nil //nolint:nilnil
+	}
+
+	if d.action == nil {
+		if _, ok := memberTypes[se.Name.Local]; ok {
+			return nil, fmt.Errorf("%w: %s outside of an action", ErrInvalid, se.Name.Local)
+		}
+
+		return nil, d.dec.Skip()
+	}
+
+	e, err := decodeEntity(d.dec, se, *d.action == model.DELETE)
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	return &model.Change{Action: *d.action, Entity: e}, nil
+}
+
+// token returns the next token of the input.
+func (d *ChangeDecoder) token() (xml.Token, error) {
+	tok, err := d.dec.Token()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	return tok, err
+}
+
+// ChangeEncoder writes OpenStreetMap change files, .osc, to an output stream.
+type ChangeEncoder struct {
+	w      *bufio.Writer
+	enc    *xml.Encoder
+	action *model.Action // the action element being written, if any
+}
+
+// NewChangeEncoder returns a new encoder that writes to wrtr.  The osmChange
+// element, with the generator if it is not empty, is written straight away.
+func NewChangeEncoder(wrtr io.Writer, generator string) (*ChangeEncoder, error) {
+	e := &ChangeEncoder{w: bufio.NewWriter(wrtr)}
+	e.enc = xml.NewEncoder(e.w)
+	e.enc.Indent("", "  ")
+
+	if _, err := e.w.WriteString(xml.Header); err != nil {
+		return nil, err
+	}
+
+	osc := xml.StartElement{
+		Name: xml.Name{Local: "osmChange"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "version"}, Value: version}},
+	}
+
+	if generator != "" {
+		osc.Attr = append(osc.Attr, xml.Attr{Name: xml.Name{Local: "generator"}, Value: generator})
+	}
+
+	if err := e.enc.EncodeToken(osc); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Encode writes a change.  Consecutive changes with the same action share an
+// action element.
+func (e *ChangeEncoder) Encode(c model.Change) error {
+	if e.action == nil || *e.action != c.Action {
+		if err := e.endAction(); err != nil {
+			return err
+		}
+
+		name, ok := actionNames[c.Action]
+		if !ok {
+			return fmt.Errorf("%w: unknown action %s", ErrInvalid, c.Action)
+		}
+
+		if err := e.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+
+		e.action = &c.Action
+	}
+
+	return encodeEntity(e.enc, c.Entity)
+}
+
+// Close ends the osmChange element and flushes the output.
+func (e *ChangeEncoder) Close() error {
+	if err := e.endAction(); err != nil {
+		return err
+	}
+
+	return closeDocument(e.w, e.enc, "osmChange")
+}
+
+// endAction ends the action element being written, if any.
+func (e *ChangeEncoder) endAction() error {
+	if e.action == nil {
+		return nil
+	}
+
+	name := actionNames[*e.action]
+	e.action = nil
+
+	return e.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+}
@@ -0,0 +1,333 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osmxml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// BatchSize is the max number of entities returned by Decoder.Decode.
+const BatchSize = 8000
+
+// Decoder reads OpenStreetMap XML from an input stream.
+type Decoder struct {
+	Header model.Header
+
+	dec     *xml.Decoder
+	pending *xml.StartElement // the element read ahead by NewDecoder
+}
+
+// NewDecoder returns a new decoder that reads from rdr.  The decoder is
+// initialized with the header, read from the osm and bounds elements.
+func NewDecoder(rdr io.Reader) (*Decoder, error) {
+	d := &Decoder{dec: xml.NewDecoder(rdr)}
+
+	se, err := d.nextElement()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: missing osm element", ErrInvalid)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if se.Name.Local != "osm" {
+		return nil, fmt.Errorf("%w: unexpected %s element", ErrInvalid, se.Name.Local)
+	}
+
+	if err := d.decodeOSM(se); err != nil {
+		return nil, err
+	}
+
+	for {
+		se, err := d.nextElement()
+		if errors.Is(err, io.EOF) {
+			return d, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if se.Name.Local != "bounds" {
+			d.pending = &se
+
+			return d, nil
+		}
+
+		if err := d.decodeBounds(se); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Decode reads the next batch of at most BatchSize entities, in input order.
+// The end of the input is reported by an io.EOF error.
+func (d *Decoder) Decode() ([]model.Entity, error) {
+	var entities []model.Entity
+
+	for len(entities) < BatchSize {
+		se, err := d.next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeEntity(d.dec, se, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if e != nil {
+			entities = append(entities, e)
+		}
+	}
+
+	if len(entities) == 0 {
+		return nil, io.EOF
+	}
+
+	return entities, nil
+}
+
+// All returns an iterator over the decoded entities.  Iteration ends at the
+// end of the input or once an error has been yielded.
+func (d *Decoder) All() iter.Seq2[model.Entity, error] {
+	return func(yield func(model.Entity, error) bool) {
+		for {
+			entities, err := d.Decode()
+			if errors.Is(err, io.EOF) {
+				return
+			} else if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			for _, e := range entities {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// next returns the next element within the osm element.
+func (d *Decoder) next() (xml.StartElement, error) {
+	if d.pending != nil {
+		se := *d.pending
+		d.pending = nil
+
+		return se, nil
+	}
+
+	return d.nextElement()
+}
+
+// nextElement returns the next start element of the input.
+func (d *Decoder) nextElement() (xml.StartElement, error) {
+	for {
+		tok, err := d.dec.Token()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				err = fmt.Errorf("%w: %w", ErrInvalid, err)
+			}
+
+			return xml.StartElement{}, err
+		}
+
+		if se, ok := tok.(xml.StartElement); ok {
+			return se, nil
+		}
+	}
+}
+
+// decodeEntity decodes the entity started by se, or skips se and returns nil
+// if it is not an entity.  Deleted entities are decoded as not visible.
+func decodeEntity(dec *xml.Decoder, se xml.StartElement, deleted bool) (model.Entity, error) {
+	switch se.Name.Local {
+	case "node":
+		var x xmlNode
+		if err := dec.DecodeElement(&x, &se); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+
+		if deleted {
+			x.Visible = "false"
+		}
+
+		return x.node()
+	case "way":
+		var x xmlWay
+		if err := dec.DecodeElement(&x, &se); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+
+		if deleted {
+			x.Visible = "false"
+		}
+
+		return x.way()
+	case "relation":
+		var x xmlRelation
+		if err := dec.DecodeElement(&x, &se); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+
+		if deleted {
+			x.Visible = "false"
+		}
+
+		return x.relation()
+	default:
+		return nil, dec.Skip()
+	}
+}
+
+func (d *Decoder) decodeOSM(se xml.StartElement) error {
+	for _, attr := range se.Attr {
+		switch attr.Name.Local {
+		case "version":
+			if attr.Value != version {
+				return fmt.Errorf("%w: unsupported version %s", ErrInvalid, attr.Value)
+			}
+		case "generator":
+			d.Header.WritingProgram = attr.Value
+		case "timestamp":
+			ts, err := time.Parse(time.RFC3339, attr.Value)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalid, err)
+			}
+
+			d.Header.OsmosisReplicationTimestamp = ts
+		}
+	}
+
+	return nil
+}
+
+func (d *Decoder) decodeBounds(se xml.StartElement) error {
+	var x xmlBounds
+	if err := d.dec.DecodeElement(&x, &se); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	var bbox model.BoundingBox
+
+	for _, c := range []struct {
+		s string
+		d *model.Degrees
+	}{
+		{x.MinLat, &bbox.Bottom},
+		{x.MinLon, &bbox.Left},
+		{x.MaxLat, &bbox.Top},
+		{x.MaxLon, &bbox.Right},
+	} {
+		deg, err := parseDegrees(0, c.s)
+		if err != nil {
+			return err
+		}
+
+		*c.d = deg
+	}
+
+	d.Header.BoundingBox = &bbox
+
+	return nil
+}
+
+func (x *xmlNode) node() (*model.Node, error) {
+	info, err := x.info()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &model.Node{ID: model.ID(x.ID), Tags: tags(x.Tags), Info: info}
+
+	// deleted nodes need not be located
+	if !info.Visible && x.Lat == "" && x.Lon == "" {
+		return n, nil
+	}
+
+	if n.Lat, err = parseDegrees(x.ID, x.Lat); err != nil {
+		return nil, err
+	}
+
+	if n.Lon, err = parseDegrees(x.ID, x.Lon); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (x *xmlWay) way() (*model.Way, error) {
+	info, err := x.info()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &model.Way{ID: model.ID(x.ID), Tags: tags(x.Tags), Info: info, NodeIDs: make([]model.ID, len(x.Nds))}
+
+	located := len(x.Nds) > 0
+
+	for i, nd := range x.Nds {
+		w.NodeIDs[i] = model.ID(nd.Ref)
+		located = located && nd.Lat != "" && nd.Lon != ""
+	}
+
+	if !located {
+		return w, nil
+	}
+
+	w.Lats = make([]model.Degrees, len(x.Nds))
+	w.Lons = make([]model.Degrees, len(x.Nds))
+
+	for i, nd := range x.Nds {
+		if w.Lats[i], err = parseDegrees(x.ID, nd.Lat); err != nil {
+			return nil, err
+		}
+
+		if w.Lons[i], err = parseDegrees(x.ID, nd.Lon); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+func (x *xmlRelation) relation() (*model.Relation, error) {
+	info, err := x.info()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &model.Relation{ID: model.ID(x.ID), Tags: tags(x.Tags), Info: info, Members: make([]model.Member, len(x.Members))}
+
+	for i, m := range x.Members {
+		t, ok := memberTypes[m.Type]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d: unknown member type %q", ErrInvalid, x.ID, m.Type)
+		}
+
+		r.Members[i] = model.Member{ID: model.ID(m.Ref), Type: t, Role: m.Role}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osmxml
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// memberTypeNames maps entity types to the member types of OSM XML.
+var memberTypeNames = map[model.EntityType]string{
+	model.NODE:     "node",
+	model.WAY:      "way",
+	model.RELATION: "relation",
+}
+
+// Encoder writes OpenStreetMap XML to an output stream.
+type Encoder struct {
+	Header model.Header
+
+	w   *bufio.Writer
+	enc *xml.Encoder
+}
+
+// NewEncoder returns a new encoder that writes to wrtr.  The osm and bounds
+// elements are written from hdr straight away.
+func NewEncoder(wrtr io.Writer, hdr model.Header) (*Encoder, error) {
+	e := &Encoder{Header: hdr, w: bufio.NewWriter(wrtr)}
+	e.enc = xml.NewEncoder(e.w)
+	e.enc.Indent("", "  ")
+
+	if _, err := e.w.WriteString(xml.Header); err != nil {
+		return nil, err
+	}
+
+	osm := xml.StartElement{
+		Name: xml.Name{Local: "osm"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "version"}, Value: version}},
+	}
+
+	if hdr.WritingProgram != "" {
+		osm.Attr = append(osm.Attr, xml.Attr{Name: xml.Name{Local: "generator"}, Value: hdr.WritingProgram})
+	}
+
+	if !hdr.OsmosisReplicationTimestamp.IsZero() {
+		osm.Attr = append(osm.Attr, xml.Attr{
+			Name:  xml.Name{Local: "timestamp"},
+			Value: hdr.OsmosisReplicationTimestamp.UTC().Format(time.RFC3339),
+		})
+	}
+
+	if err := e.enc.EncodeToken(osm); err != nil {
+		return nil, err
+	}
+
+	if bbox := hdr.BoundingBox; bbox != nil {
+		bounds := struct {
+			XMLName xml.Name `xml:"bounds"`
+			xmlBounds
+		}{
+			xmlBounds: xmlBounds{
+				MinLat: formatDegrees(bbox.Bottom),
+				MinLon: formatDegrees(bbox.Left),
+				MaxLat: formatDegrees(bbox.Top),
+				MaxLon: formatDegrees(bbox.Right),
+			},
+		}
+
+		if err := e.enc.Encode(bounds); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// Encode writes an entity.
+func (e *Encoder) Encode(entity model.Entity) error {
+	return encodeEntity(e.enc, entity)
+}
+
+// EncodeBatch writes an array of entities.
+func (e *Encoder) EncodeBatch(entities []model.Entity) error {
+	for _, entity := range entities {
+		if err := e.Encode(entity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close ends the osm element and flushes the output.
+func (e *Encoder) Close() error {
+	return closeDocument(e.w, e.enc, "osm")
+}
+
+// encodeEntity writes the element of entity with enc.
+func encodeEntity(enc *xml.Encoder, entity model.Entity) error {
+	switch entity := entity.(type) {
+	case *model.Node:
+		return enc.Encode(toXMLNode(entity))
+	case *model.Way:
+		return enc.Encode(toXMLWay(entity))
+	case *model.Relation:
+		return enc.Encode(toXMLRelation(entity))
+	}
+
+	return nil
+}
+
+// closeDocument ends the root element, name, and flushes the output.
+func closeDocument(w *bufio.Writer, enc *xml.Encoder, name string) error {
+	if err := enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}}); err != nil {
+		return err
+	}
+
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	if err := w.WriteByte('\n'); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func toXMLNode(n *model.Node) *xmlNode {
+	return &xmlNode{
+		xmlInfo: toXMLInfo(n.ID, n.Info),
+		Lat:     formatDegrees(n.Lat),
+		Lon:     formatDegrees(n.Lon),
+		Tags:    toXMLTags(n.Tags),
+	}
+}
+
+func toXMLWay(w *model.Way) *xmlWay {
+	located := len(w.Lats) == len(w.NodeIDs) && len(w.Lons) == len(w.NodeIDs)
+
+	nds := make([]xmlNd, len(w.NodeIDs))
+	for i, id := range w.NodeIDs {
+		nds[i].Ref = int64(id)
+
+		if located {
+			nds[i].Lat = formatDegrees(w.Lats[i])
+			nds[i].Lon = formatDegrees(w.Lons[i])
+		}
+	}
+
+	return &xmlWay{xmlInfo: toXMLInfo(w.ID, w.Info), Nds: nds, Tags: toXMLTags(w.Tags)}
+}
+
+func toXMLRelation(r *model.Relation) *xmlRelation {
+	members := make([]xmlMember, len(r.Members))
+	for i, m := range r.Members {
+		members[i] = xmlMember{Type: memberTypeNames[m.Type], Ref: int64(m.ID), Role: m.Role}
+	}
+
+	return &xmlRelation{xmlInfo: toXMLInfo(r.ID, r.Info), Members: members, Tags: toXMLTags(r.Tags)}
+}
+
+// toXMLTags returns the tags, sorted by key.
+func toXMLTags(tags map[string]string) []xmlTag {
+	xs := make([]xmlTag, 0, len(tags))
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		xs = append(xs, xmlTag{K: k, V: tags[k]})
+	}
+
+	return xs
+}
@@ -0,0 +1,320 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osmxml_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/osmxml"
+)
+
+const snippet = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="osmium/1.14.0" timestamp="2024-10-28T21:21:30Z">
+  <bounds minlat="51.28" minlon="-0.51" maxlat="51.69" maxlon="0.33"/>
+  <changeset id="9"/>
+  <node id="1" version="2" timestamp="2012-04-09T21:37:39Z" changeset="7" uid="3" user="Kjc &amp; co" lat="51.5" lon="-0.1">
+    <tag k="amenity" v="bench"/>
+  </node>
+  <node id="2" version="1" visible="false" lat="51.6" lon="-0.2"/>
+  <way id="3" version="1">
+    <nd ref="1" lat="51.5" lon="-0.1"/>
+    <nd ref="2" lat="51.6" lon="-0.2"/>
+    <tag k="highway" v="footway"/>
+  </way>
+  <relation id="4" version="1">
+    <member type="way" ref="3" role="outer"/>
+    <member type="node" ref="1" role=""/>
+    <tag k="type" v="multipolygon"/>
+  </relation>
+</osm>
+`
+
+func TestDecode(t *testing.T) {
+	d, err := osmxml.NewDecoder(strings.NewReader(snippet))
+	require.NoError(t, err)
+
+	assert.Equal(t, "osmium/1.14.0", d.Header.WritingProgram)
+	assert.Equal(t, time.Date(2024, 10, 28, 21, 21, 30, 0, time.UTC), d.Header.OsmosisReplicationTimestamp)
+	assert.Equal(t, &model.BoundingBox{Top: 51.69, Left: -0.51, Bottom: 51.28, Right: 0.33}, d.Header.BoundingBox)
+
+	var entities []model.Entity
+
+	for e, err := range d.All() {
+		require.NoError(t, err)
+
+		entities = append(entities, e)
+	}
+
+	require.Len(t, entities, 4)
+
+	assert.Equal(t, &model.Node{
+		ID:   1,
+		Tags: map[string]string{"amenity": "bench"},
+		Info: &model.Info{
+			Version:   2,
+			UID:       3,
+			Timestamp: time.Date(2012, 4, 9, 21, 37, 39, 0, time.UTC),
+			Changeset: 7,
+			User:      "Kjc & co",
+			Visible:   true,
+		},
+		Lat: 51.5,
+		Lon: -0.1,
+	}, entities[0])
+
+	assert.False(t, entities[1].GetInfo().Visible)
+
+	assert.Equal(t, &model.Way{
+		ID:      3,
+		Tags:    map[string]string{"highway": "footway"},
+		Info:    &model.Info{Version: 1, Visible: true},
+		NodeIDs: []model.ID{1, 2},
+		Lats:    []model.Degrees{51.5, 51.6},
+		Lons:    []model.Degrees{-0.1, -0.2},
+	}, entities[2])
+
+	assert.Equal(t, []model.Member{
+		{ID: 3, Type: model.WAY, Role: "outer"},
+		{ID: 1, Type: model.NODE, Role: ""},
+	}, entities[3].(*model.Relation).Members)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, doc := range []string{
+		``,
+		`<gpx/>`,
+		`<osm version="0.5"/>`,
+		`<osm version="0.6"><node id="1" lat="north" lon="0"/></osm>`,
+		`<osm version="0.6"><relation id="1"><member type="area" ref="1" role=""/></relation></osm>`,
+		`<osm version="0.6"><node id="1" lat="0" lon="0">`,
+	} {
+		t.Run(doc, func(t *testing.T) {
+			d, err := osmxml.NewDecoder(strings.NewReader(doc))
+			if err == nil {
+				_, err = d.Decode()
+			}
+
+			assert.ErrorIs(t, err, osmxml.ErrInvalid)
+		})
+	}
+}
+
+func TestSampleMatchesPBF(t *testing.T) {
+	in, err := os.Open("../testdata/sample.osm")
+	require.NoError(t, err)
+	defer in.Close()
+
+	d, err := osmxml.NewDecoder(in)
+	require.NoError(t, err)
+
+	assert.Equal(t, decodePBF(t), collect(t, d))
+}
+
+func TestRoundTrip(t *testing.T) {
+	entities := decodePBF(t)
+	hdr := model.Header{
+		BoundingBox:                 &model.BoundingBox{Top: 51.78, Left: -0.25, Bottom: 51.76, Right: -0.21},
+		WritingProgram:              "pbf",
+		OsmosisReplicationTimestamp: time.Date(2024, 10, 28, 21, 21, 30, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+
+	e, err := osmxml.NewEncoder(&buf, hdr)
+	require.NoError(t, err)
+	require.NoError(t, e.EncodeBatch(entities))
+	require.NoError(t, e.Close())
+
+	d, err := osmxml.NewDecoder(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, hdr, d.Header)
+	assert.Equal(t, entities, collect(t, d))
+}
+
+func decodePBF(t *testing.T) []model.Entity {
+	t.Helper()
+
+	in, err := os.Open("../testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer in.Close()
+
+	fi, err := in.Stat()
+	require.NoError(t, err)
+
+	d, err := pbf.NewDecoderAt(context.Background(), in, fi.Size())
+	require.NoError(t, err)
+
+	var entities []model.Entity
+
+	for e, err := range d.All() {
+		require.NoError(t, err)
+
+		entities = append(entities, e)
+	}
+
+	return entities
+}
+
+func collect(t *testing.T, d *osmxml.Decoder) []model.Entity {
+	t.Helper()
+
+	var entities []model.Entity
+
+	for e, err := range d.All() {
+		require.NoError(t, err)
+
+		entities = append(entities, e)
+	}
+
+	return entities
+}
+
+const changes = `<?xml version="1.0" encoding="UTF-8"?>
+<osmChange version="0.6" generator="osmdbt-create-diff/0.6">
+  <modify>
+    <node id="1" version="3" timestamp="2024-10-29T08:00:00Z" lat="51.7" lon="-0.3"/>
+  </modify>
+  <create>
+    <way id="5" version="1"><nd ref="1"/><nd ref="2"/></way>
+  </create>
+  <delete>
+    <node id="2" version="2" timestamp="2024-10-29T09:00:00Z"/>
+    <relation id="4" version="2"/>
+  </delete>
+</osmChange>
+`
+
+func TestChangeDecoder(t *testing.T) {
+	var gz bytes.Buffer
+
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write([]byte(changes))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	for name, in := range map[string][]byte{"osc": []byte(changes), "osc.gz": gz.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			d, err := osmxml.NewChangeDecoder(bytes.NewReader(in))
+			require.NoError(t, err)
+			assert.Equal(t, "osmdbt-create-diff/0.6", d.Generator)
+
+			var got []model.Change
+
+			for c, err := range d.All() {
+				require.NoError(t, err)
+
+				got = append(got, c)
+			}
+
+			assert.Equal(t, []model.Change{
+				{Action: model.MODIFY, Entity: &model.Node{
+					ID:   1,
+					Tags: map[string]string{},
+					Info: &model.Info{Version: 3, Timestamp: time.Date(2024, 10, 29, 8, 0, 0, 0, time.UTC), Visible: true},
+					Lat:  51.7,
+					Lon:  -0.3,
+				}},
+				{Action: model.CREATE, Entity: &model.Way{
+					ID:      5,
+					Tags:    map[string]string{},
+					Info:    &model.Info{Version: 1, Visible: true},
+					NodeIDs: []model.ID{1, 2},
+				}},
+				{Action: model.DELETE, Entity: &model.Node{
+					ID:   2,
+					Tags: map[string]string{},
+					Info: &model.Info{Version: 2, Timestamp: time.Date(2024, 10, 29, 9, 0, 0, 0, time.UTC)},
+				}},
+				{Action: model.DELETE, Entity: &model.Relation{
+					ID:      4,
+					Tags:    map[string]string{},
+					Info:    &model.Info{Version: 2},
+					Members: []model.Member{},
+				}},
+			}, got)
+		})
+	}
+}
+
+func TestChangeDecoderErrors(t *testing.T) {
+	for _, doc := range []string{
+		``,
+		`<osm version="0.6"/>`,
+		`<osmChange version="0.5"/>`,
+		`<osmChange version="0.6"><node id="1" lat="0" lon="0"/></osmChange>`,
+		`<osmChange version="0.6"><create><node id="1" lat="0" lon="0"/></create>`,
+	} {
+		t.Run(doc, func(t *testing.T) {
+			d, err := osmxml.NewChangeDecoder(strings.NewReader(doc))
+			if err == nil {
+				_, err = d.Decode()
+			}
+
+			assert.ErrorIs(t, err, osmxml.ErrInvalid)
+		})
+	}
+}
+
+func TestChangeEncoderRoundTrip(t *testing.T) {
+	d, err := osmxml.NewChangeDecoder(strings.NewReader(changes))
+	require.NoError(t, err)
+
+	var want []model.Change
+
+	for c, err := range d.All() {
+		require.NoError(t, err)
+
+		want = append(want, c)
+	}
+
+	var buf bytes.Buffer
+
+	e, err := osmxml.NewChangeEncoder(&buf, "pbf")
+	require.NoError(t, err)
+
+	for _, c := range want {
+		require.NoError(t, e.Encode(c))
+	}
+
+	require.NoError(t, e.Close())
+	assert.Equal(t, 3, strings.Count(buf.String(), "</modify>")+
+		strings.Count(buf.String(), "</create>")+strings.Count(buf.String(), "</delete>"))
+
+	d, err = osmxml.NewChangeDecoder(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "pbf", d.Generator)
+
+	var got []model.Change
+
+	for c, err := range d.All() {
+		require.NoError(t, err)
+
+		got = append(got, c)
+	}
+
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package osmxml decodes and encodes OpenStreetMap XML, producing and consuming
+the same model values as the PBF Decoder and Encoder.
+
+The generator of the osm element maps to the WritingProgram of the header,
+its timestamp to the OsmosisReplicationTimestamp, and the bounds element to
+the BoundingBox.  Ways hold the coordinates of their nodes when every nd
+element has lat and lon attributes, as osmium writes for LocationsOnWays.
+*/
+package osmxml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// ErrInvalid is returned when the input is not valid OpenStreetMap XML.
+var ErrInvalid = errors.New("invalid OSM XML")
+
+// version is the version of the OSM API whose XML is supported.
+const version = "0.6"
+
+type xmlBounds struct {
+	MinLat string `xml:"minlat,attr"`
+	MinLon string `xml:"minlon,attr"`
+	MaxLat string `xml:"maxlat,attr"`
+	MaxLon string `xml:"maxlon,attr"`
+}
+
+// xmlInfo holds the attributes common to nodes, ways and relations.
+type xmlInfo struct {
+	ID        int64  `xml:"id,attr"`
+	Version   int32  `xml:"version,attr,omitempty"`
+	Timestamp string `xml:"timestamp,attr,omitempty"`
+	Changeset int64  `xml:"changeset,attr,omitempty"`
+	UID       int32  `xml:"uid,attr,omitempty"`
+	User      string `xml:"user,attr,omitempty"`
+	Visible   string `xml:"visible,attr,omitempty"`
+}
+
+type xmlTag struct {
+	K string `xml:"k,attr"`
+	V string `xml:"v,attr"`
+}
+
+type xmlNode struct {
+	XMLName xml.Name `xml:"node"`
+	xmlInfo
+	Lat  string   `xml:"lat,attr"`
+	Lon  string   `xml:"lon,attr"`
+	Tags []xmlTag `xml:"tag"`
+}
+
+type xmlNd struct {
+	Ref int64  `xml:"ref,attr"`
+	Lat string `xml:"lat,attr,omitempty"`
+	Lon string `xml:"lon,attr,omitempty"`
+}
+
+type xmlWay struct {
+	XMLName xml.Name `xml:"way"`
+	xmlInfo
+	Nds  []xmlNd  `xml:"nd"`
+	Tags []xmlTag `xml:"tag"`
+}
+
+type xmlMember struct {
+	Type string `xml:"type,attr"`
+	Ref  int64  `xml:"ref,attr"`
+	Role string `xml:"role,attr"`
+}
+
+type xmlRelation struct {
+	XMLName xml.Name `xml:"relation"`
+	xmlInfo
+	Members []xmlMember `xml:"member"`
+	Tags    []xmlTag    `xml:"tag"`
+}
+
+// memberTypes maps the member types of OSM XML to entity types.
+var memberTypes = map[string]model.EntityType{
+	"node":     model.NODE,
+	"way":      model.WAY,
+	"relation": model.RELATION,
+}
+
+func (x *xmlInfo) info() (*model.Info, error) {
+	info := &model.Info{
+		Version:   x.Version,
+		UID:       model.UID(x.UID),
+		Changeset: x.Changeset,
+		User:      x.User,
+		Visible:   x.Visible != "false",
+	}
+
+	if x.Timestamp != "" {
+		ts, err := time.Parse(time.RFC3339, x.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %d: %w", ErrInvalid, x.ID, err)
+		}
+
+		info.Timestamp = ts
+	}
+
+	return info, nil
+}
+
+func toXMLInfo(id model.ID, info *model.Info) xmlInfo {
+	x := xmlInfo{ID: int64(id)}
+	if info == nil {
+		return x
+	}
+
+	x.Version = info.Version
+	x.Changeset = info.Changeset
+	x.UID = int32(info.UID)
+	x.User = info.User
+
+	if !info.Timestamp.IsZero() {
+		x.Timestamp = info.Timestamp.UTC().Format(time.RFC3339)
+	}
+
+	if !info.Visible {
+		x.Visible = "false"
+	}
+
+	return x
+}
+
+func tags(xs []xmlTag) map[string]string {
+	tags := make(map[string]string, len(xs))
+	for _, t := range xs {
+		tags[t.K] = t.V
+	}
+
+	return tags
+}
+
+func parseDegrees(id int64, s string) (model.Degrees, error) {
+	d, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %d: %w", ErrInvalid, id, err)
+	}
+
+	return model.Degrees(d), nil
+}
+
+func formatDegrees(d model.Degrees) string {
+	return strconv.FormatFloat(float64(d), 'f', -1, 64)
+}
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/o5m"
 )
 
 type entityDigest struct {
@@ -36,21 +37,21 @@ type multisetHasher struct {
 	xor [4]uint64
 }
 
+var roundTripDatasets = []struct {
+	name              string
+	path              string
+	compareNodeDigest bool
+}{
+	{name: "sample", path: "testdata/sample.osm.pbf", compareNodeDigest: true},
+	{name: "bremen", path: "testdata/bremen.osm.pbf", compareNodeDigest: true},
+	{name: "london", path: "testdata/greater-london.osm.pbf", compareNodeDigest: true},
+	{name: "san-francisco", path: "testdata/san-francisco.osm.pbf", compareNodeDigest: true},
+}
+
 func TestRoundTripOSMPBFDatasets(t *testing.T) {
 	t.Parallel()
 
-	testCases := []struct {
-		name              string
-		path              string
-		compareNodeDigest bool
-	}{
-		{name: "sample", path: "testdata/sample.osm.pbf", compareNodeDigest: true},
-		{name: "bremen", path: "testdata/bremen.osm.pbf", compareNodeDigest: true},
-		{name: "london", path: "testdata/greater-london.osm.pbf", compareNodeDigest: true},
-		{name: "san-francisco", path: "testdata/san-francisco.osm.pbf", compareNodeDigest: true},
-	}
-
-	for _, tc := range testCases {
+	for _, tc := range roundTripDatasets {
 		t.Run(tc.name, func(t *testing.T) {
 			input, err := os.Open(tc.path)
 			if err != nil {
@@ -103,7 +104,94 @@ func TestRoundTripOSMPBFDatasets(t *testing.T) {
 	}
 }
 
-func digestAndEncode(dec *Decoder, enc *Encoder) (entityDigest, error) {
+func TestRoundTripOSMPBFO5MDatasets(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range roundTripDatasets {
+		t.Run(tc.name, func(t *testing.T) {
+			input, err := os.Open(tc.path)
+			if err != nil {
+				t.Fatalf("open %s: %v", tc.path, err)
+			}
+			defer input.Close()
+
+			decoded, err := NewDecoder(context.Background(), input)
+			if err != nil {
+				t.Fatalf("create source decoder: %v", err)
+			}
+			defer decoded.Close()
+
+			var o5mEncoded bytes.Buffer
+			o5mEncoder, err := o5m.NewEncoder(&o5mEncoded, decoded.Header)
+			if err != nil {
+				t.Fatalf("create o5m encoder: %v", err)
+			}
+
+			sourceDigest, err := digestAndEncode(decoded, o5mEncoder)
+			if err != nil {
+				t.Fatalf("digest and encode source %s: %v", tc.path, err)
+			}
+
+			if err := o5mEncoder.Close(); err != nil {
+				t.Fatalf("close o5m encoder: %v", err)
+			}
+
+			o5mDecoded, err := o5m.NewDecoder(bytes.NewReader(o5mEncoded.Bytes()))
+			if err != nil {
+				t.Fatalf("create o5m decoder: %v", err)
+			}
+
+			var encoded bytes.Buffer
+			reencoded, err := NewEncoder(&encoded)
+			if err != nil {
+				t.Fatalf("create encoder: %v", err)
+			}
+
+			if _, err := digestAndEncode(o5mDecoded, reencoded); err != nil {
+				t.Fatalf("encode o5m %s: %v", tc.path, err)
+			}
+
+			if err := reencoded.Close(); err != nil {
+				t.Fatalf("close encoder: %v", err)
+			}
+
+			roundTrip, err := NewDecoder(context.Background(), bytes.NewReader(encoded.Bytes()))
+			if err != nil {
+				t.Fatalf("create round-trip decoder: %v", err)
+			}
+			defer roundTrip.Close()
+
+			roundTripDigest, err := digestDecoder(roundTrip)
+			if err != nil {
+				t.Fatalf("digest round-trip %s: %v", tc.path, err)
+			}
+
+			if sourceDigest.total != roundTripDigest.total ||
+				sourceDigest.nodes != roundTripDigest.nodes ||
+				sourceDigest.ways != roundTripDigest.ways ||
+				sourceDigest.relations != roundTripDigest.relations ||
+				sourceDigest.waySum != roundTripDigest.waySum ||
+				sourceDigest.relSum != roundTripDigest.relSum {
+				t.Fatalf("source and round-trip digests differ\nsource: %+v\nround-trip: %+v", sourceDigest, roundTripDigest)
+			}
+			if tc.compareNodeDigest && sourceDigest.nodeSum != roundTripDigest.nodeSum {
+				t.Fatalf("source and round-trip node digests differ\nsource: %+v\nround-trip: %+v", sourceDigest, roundTripDigest)
+			}
+		})
+	}
+}
+
+// entityDecoder is satisfied by the PBF and o5m decoders.
+type entityDecoder interface {
+	Decode() ([]model.Entity, error)
+}
+
+// entityEncoder is satisfied by the PBF and o5m encoders.
+type entityEncoder interface {
+	EncodeBatch(entities []model.Entity) error
+}
+
+func digestAndEncode(dec entityDecoder, enc entityEncoder) (entityDigest, error) {
 	hasher := multisetHasher{}
 	nodeHasher := multisetHasher{}
 	wayHasher := multisetHasher{}
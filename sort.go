@@ -0,0 +1,292 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pbf
+
+import (
+	"bufio"
+	"cmp"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/destel/rill"
+
+	"m4o.io/pbf/v2/internal/decoder"
+	"m4o.io/pbf/v2/internal/encoder"
+	"m4o.io/pbf/v2/model"
+)
+
+// DefaultSortRunSize is the default number of entities a sorted encoder holds
+// in memory before spilling them to the temporary store.
+const DefaultSortRunSize = 1 << 20
+
+// ErrSort is returned when a sorted encoder cannot spill or merge its runs.
+var ErrSort = errors.New("sort entities")
+
+// sorter orders entities by type, then ID.  Entities are collected into runs
+// of at most runSize entities; full runs are sorted and spilled to temporary
+// files, which are merged with the final in-memory run.
+type sorter struct {
+	store     string            // where the directory of spilled runs is made
+	runSize   int               // the max number of entities held in memory
+	precision encoder.Precision // the precision runs are spilled with
+
+	dir  string         // the directory of spilled runs, once made
+	run  []model.Entity // the in-memory run
+	runs []string       // the paths of the spilled runs
+}
+
+// newSorter returns a sorter that spills runs of runSize entities to a
+// directory made in store, or in the default directory for temporary files if
+// store is empty.
+func newSorter(store string, runSize int, p encoder.Precision) *sorter {
+	return &sorter{store: store, runSize: runSize, precision: p}
+}
+
+// add adds entities to the in-memory run, spilling it whenever it is full.
+func (s *sorter) add(entities []model.Entity) error {
+	for _, e := range entities {
+		s.run = append(s.run, e)
+
+		if len(s.run) == s.runSize {
+			if err := s.spill(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// spill sorts the in-memory run and writes it to a new temporary file.
+func (s *sorter) spill() error {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp(s.store, "sort")
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCreateTempDir, err)
+		}
+
+		s.dir = dir
+	}
+
+	name := path.Join(s.dir, fmt.Sprintf("run-%d.pbf", len(s.runs)))
+
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("%w %s: %w", ErrCreateTempFile, name, err)
+	}
+	defer f.Close()
+
+	slices.SortStableFunc(s.run, model.Compare)
+
+	w := bufio.NewWriter(f)
+	// the coordinates of ways are kept whether or not they end up written
+	encode := encoder.GenerateBatchEncoder(s.precision, true)
+
+	for batch := range encoder.SplitSorted(s.run, encoder.EntityLimit) {
+		block, err := encode(batch)
+		if err != nil {
+			return err
+		}
+
+		if err := encoder.SaveBlock(w, rill.Wrap(encoder.Pack(block, encoder.LZ4, DefaultCompressionLevel))); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	s.runs = append(s.runs, name)
+
+	clear(s.run)
+	s.run = s.run[:0]
+
+	return f.Close()
+}
+
+// sorted returns an iterator over the added entities, ordered by type, then
+// ID.  Entities with the same type and ID keep the order they were added in.
+func (s *sorter) sorted(ctx context.Context) iter.Seq2[model.Entity, error] {
+	return s.merged(ctx, nil)
+}
+
+// merged is sorted, merged with the entities of the blocks of first, which
+// are in order and precede the added entities with the same type and ID.
+func (s *sorter) merged(ctx context.Context, first io.Reader) iter.Seq2[model.Entity, error] {
+	return func(yield func(model.Entity, error) bool) {
+		slices.SortStableFunc(s.run, model.Compare)
+
+		cursors := make(runCursors, 0, len(s.runs)+2)
+
+		defer func() {
+			for _, c := range cursors {
+				c.stop()
+			}
+		}()
+
+		if first != nil {
+			cursors = append(cursors, newRunCursor(-1, readRun(ctx, first)))
+		}
+
+		for i, name := range s.runs {
+			f, err := os.Open(name)
+			if err != nil {
+				yield(nil, err)
+
+				return
+			}
+			defer f.Close()
+
+			cursors = append(cursors, newRunCursor(i, readRun(ctx, f)))
+		}
+
+		cursors = append(cursors, newRunCursor(len(s.runs), func(yield func(model.Entity, error) bool) {
+			for _, e := range s.run {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}))
+
+		for _, c := range cursors {
+			if err := c.advance(); err != nil {
+				yield(nil, err)
+
+				return
+			}
+		}
+
+		heap.Init(&cursors)
+
+		for cursors[0].head != nil {
+			c := cursors[0]
+			if !yield(c.head, nil) {
+				return
+			}
+
+			if err := c.advance(); err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			heap.Fix(&cursors, 0)
+		}
+	}
+}
+
+// close removes the spilled runs.
+func (s *sorter) close() error {
+	if s.dir == "" {
+		return nil
+	}
+
+	return os.RemoveAll(s.dir)
+}
+
+// readRun returns an iterator over the entities of a spilled run.
+func readRun(ctx context.Context, f io.Reader) iter.Seq2[model.Entity, error] {
+	return func(yield func(model.Entity, error) bool) {
+		all := decoder.NewFilter(decoder.AllTypes, false, nil)
+
+		for blob, err := range decoder.GenerateBlobReader(ctx, bufio.NewReader(f)) {
+			if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			entities, err := decoder.DecodeBlob(blob, all)
+			if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			for _, e := range entities {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// runCursor is the position of the k-way merge within a run.
+type runCursor struct {
+	index int          // the order the run was added in
+	head  model.Entity // the next entity of the run, or nil once exhausted
+	next  func() (model.Entity, error, bool)
+	stop  func()
+}
+
+func newRunCursor(index int, run iter.Seq2[model.Entity, error]) *runCursor {
+	next, stop := iter.Pull2(run)
+
+	return &runCursor{index: index, next: next, stop: stop}
+}
+
+// advance moves the cursor to the next entity of the run.
+func (c *runCursor) advance() error {
+	e, err, ok := c.next()
+	if !ok {
+		c.head = nil
+
+		return nil
+	}
+
+	c.head = e
+
+	return err
+}
+
+// runCursors is a min-heap of cursors, ordered by their heads; exhausted
+// cursors sink to the bottom.
+type runCursors []*runCursor
+
+func (h runCursors) Len() int { return len(h) }
+
+func (h runCursors) Less(i, j int) bool {
+	a, b := h[i], h[j]
+
+	switch {
+	case a.head == nil:
+		return false
+	case b.head == nil:
+		return true
+	}
+
+	return cmp.Or(model.Compare(a.head, b.head), cmp.Compare(a.index, b.index)) < 0
+}
+
+func (h runCursors) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *runCursors) Push(x any) { *h = append(*h, x.(*runCursor)) }
+
+func (h *runCursors) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return c
+}
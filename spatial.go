@@ -0,0 +1,312 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pbf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/destel/rill"
+
+	"m4o.io/pbf/v2/internal/decoder"
+	"m4o.io/pbf/v2/model"
+	"m4o.io/pbf/v2/tagfilter"
+)
+
+var (
+	// ErrUnsupportedSpatialStrategy is returned when a spatial strategy is
+	// unknown or cannot be used by the decoder.
+	ErrUnsupportedSpatialStrategy = errors.New("unsupported spatial strategy")
+)
+
+// Area is a region of the earth's surface.  Both *model.BoundingBox and
+// *model.Polygon are areas.
+type Area interface {
+	// Contains checks if the area contains the lat lng point.
+	Contains(lat model.Degrees, lon model.Degrees) bool
+}
+
+// SpatialStrategy selects which of the entities that reference the nodes
+// within an area are kept by a spatial filter.  The strategies follow those
+// of osmium extract.
+type SpatialStrategy int
+
+const (
+	// SimpleStrategy keeps the nodes within the area, the ways that
+	// reference at least one of those nodes, and the relations that have at
+	// least one of those nodes or ways as a member.  Kept ways may reference
+	// nodes that are not kept.  It makes a single pass over the input, which
+	// must hold nodes before ways before relations.
+	SimpleStrategy SpatialStrategy = iota
+
+	// CompleteWaysStrategy is SimpleStrategy, additionally keeping every
+	// node referenced by a kept way.
+	CompleteWaysStrategy
+
+	// SmartStrategy is CompleteWaysStrategy, additionally keeping every
+	// member way, and its nodes, of the kept multipolygon relations.
+	SmartStrategy
+)
+
+var spatialStrategyNames = [...]string{"simple", "complete_ways", "smart"}
+
+func (s SpatialStrategy) String() string {
+	if s < 0 || int(s) >= len(spatialStrategyNames) {
+		return fmt.Sprintf("SpatialStrategy(%d)", int(s))
+	}
+
+	return spatialStrategyNames[s]
+}
+
+// ParseSpatialStrategy returns the strategy with the given name: simple,
+// complete_ways or smart.
+func ParseSpatialStrategy(name string) (SpatialStrategy, error) {
+	for i, n := range spatialStrategyNames {
+		if n == name {
+			return SpatialStrategy(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrUnsupportedSpatialStrategy, name)
+}
+
+// spatialOptions holds the configuration of a spatial filter.
+type spatialOptions struct {
+	area     Area
+	strategy SpatialStrategy
+}
+
+// idSet is a set of entity IDs.
+type idSet map[model.ID]struct{}
+
+func (s idSet) add(id model.ID) {
+	s[id] = struct{}{}
+}
+
+func (s idSet) has(id model.ID) bool {
+	_, ok := s[id]
+
+	return ok
+}
+
+func (s idSet) hasAny(ids []model.ID) bool {
+	for _, id := range ids {
+		if s.has(id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// spatialFilter tracks the IDs of the entities kept by a spatial filter.
+type spatialFilter struct {
+	*spatialOptions
+
+	nodes     idSet
+	ways      idSet
+	relations idSet
+}
+
+func newSpatialFilter(o *spatialOptions) *spatialFilter {
+	return &spatialFilter{
+		spatialOptions: o,
+		nodes:          idSet{},
+		ways:           idSet{},
+		relations:      idSet{},
+	}
+}
+
+// filterSimple applies SimpleStrategy to entities of all types, which are
+// processed sequentially in the order they arrive.  Only kept entities of the
+// given types, and whose tags match tags when it is not nil, are returned.
+func (f *spatialFilter) filterSimple(
+	in <-chan rill.Try[[]model.Entity],
+	types decoder.TypeSet,
+	tags *tagfilter.Expr,
+) <-chan rill.Try[[]model.Entity] {
+	return rill.OrderedFilterMap(in, 1, func(batch []model.Entity) ([]model.Entity, bool, error) {
+		kept := make([]model.Entity, 0, len(batch))
+
+		for _, e := range batch {
+			if f.keep(e) && types.Has(model.TypeOf(e)) && (tags == nil || tags.Match(e.GetTags())) {
+				kept = append(kept, e)
+			}
+		}
+
+		return kept, len(kept) > 0, nil
+	})
+}
+
+// keep reports whether e is kept by SimpleStrategy, recording its ID if so.
+func (f *spatialFilter) keep(e model.Entity) bool {
+	switch e := e.(type) {
+	case *model.Node:
+		if f.area.Contains(e.Lat, e.Lon) {
+			f.nodes.add(e.ID)
+
+			return true
+		}
+	case *model.Way:
+		if f.nodes.hasAny(e.NodeIDs) {
+			f.ways.add(e.ID)
+
+			return true
+		}
+	case *model.Relation:
+		if f.hasKeptMember(e) {
+			f.relations.add(e.ID)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasKeptMember reports whether any member of r has been kept.
+func (f *spatialFilter) hasKeptMember(r *model.Relation) bool {
+	for _, m := range r.Members {
+		switch m.Type {
+		case model.NODE:
+			if f.nodes.has(m.ID) {
+				return true
+			}
+		case model.WAY:
+			if f.ways.has(m.ID) {
+				return true
+			}
+		case model.RELATION:
+			if f.relations.has(m.ID) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// selectAt makes a pass over rdr for each entity type, selecting the IDs of
+// the entities to be kept by the CompleteWaysStrategy or SmartStrategy.
+func (f *spatialFilter) selectAt(
+	ctx context.Context,
+	rdr io.ReaderAt,
+	size int64,
+	cfg *decoderOptions,
+	hdr model.Header,
+) error {
+	pass := func(t model.EntityType, fn func(e model.Entity)) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		entities := decodeAt(ctx, rdr, size, cfg, decoder.NewFilter(decoder.NewTypeSet(t), isSorted(hdr), nil))
+
+		return rill.ForEach(entities, 1, func(batch []model.Entity) error {
+			for _, e := range batch {
+				fn(e)
+			}
+
+			return nil
+		})
+	}
+
+	// referenced holds the nodes that complete the kept ways and
+	// multipolygonWays the member ways of kept multipolygons.
+	referenced := idSet{}
+	multipolygonWays := idSet{}
+
+	if err := pass(model.NODE, func(e model.Entity) { f.keep(e) }); err != nil {
+		return err
+	}
+
+	err := pass(model.WAY, func(e model.Entity) {
+		if f.keep(e) {
+			for _, id := range e.(*model.Way).NodeIDs {
+				referenced.add(id)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = pass(model.RELATION, func(e model.Entity) {
+		r := e.(*model.Relation)
+		if !f.keep(r) || f.strategy != SmartStrategy || r.Tags["type"] != "multipolygon" {
+			return
+		}
+
+		for _, m := range r.Members {
+			if m.Type == model.WAY && !f.ways.has(m.ID) {
+				multipolygonWays.add(m.ID)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(multipolygonWays) > 0 {
+		err = pass(model.WAY, func(e model.Entity) {
+			w := e.(*model.Way)
+			if !multipolygonWays.has(w.ID) {
+				return
+			}
+
+			f.ways.add(w.ID)
+
+			for _, id := range w.NodeIDs {
+				referenced.add(id)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for id := range referenced {
+		f.nodes.add(id)
+	}
+
+	return nil
+}
+
+// filterSelected keeps the entities whose IDs were chosen by selectAt.
+func (f *spatialFilter) filterSelected(in <-chan rill.Try[[]model.Entity], n int) <-chan rill.Try[[]model.Entity] {
+	return rill.OrderedFilterMap(in, n, func(batch []model.Entity) ([]model.Entity, bool, error) {
+		kept := make([]model.Entity, 0, len(batch))
+
+		for _, e := range batch {
+			var selected idSet
+
+			switch e.(type) {
+			case *model.Node:
+				selected = f.nodes
+			case *model.Way:
+				selected = f.ways
+			case *model.Relation:
+				selected = f.relations
+			}
+
+			if selected.has(e.GetID()) {
+				kept = append(kept, e)
+			}
+		}
+
+		return kept, len(kept) > 0, nil
+	})
+}
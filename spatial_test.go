@@ -0,0 +1,216 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pbf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2/model"
+)
+
+// sampleArea covers the north-west corner of the sample's nodes.
+var sampleArea = &model.BoundingBox{Top: 51.78, Left: -0.25, Bottom: 51.766, Right: -0.23}
+
+func TestSpatialFilterSimple(t *testing.T) {
+	in, err := os.Open("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer in.Close()
+
+	dec, err := NewDecoder(context.Background(), in, WithSpatialFilter(sampleArea, SimpleStrategy))
+	require.NoError(t, err)
+	defer dec.Close()
+
+	nodes, ways, relations := decodeByType(t, dec)
+
+	require.NotEmpty(t, nodes)
+	require.NotEmpty(t, ways)
+
+	for _, n := range nodes {
+		assert.True(t, sampleArea.Contains(n.Lat, n.Lon), "node %d outside area", n.ID)
+	}
+
+	for _, w := range ways {
+		assert.True(t, referencesAny(nodes, w.NodeIDs), "way %d references no kept node", w.ID)
+	}
+
+	for _, r := range relations {
+		var found bool
+
+		for _, m := range r.Members {
+			switch m.Type {
+			case model.NODE:
+				_, found = nodes[m.ID]
+			case model.WAY:
+				_, found = ways[m.ID]
+			case model.RELATION:
+				_, found = relations[m.ID]
+			}
+
+			if found {
+				break
+			}
+		}
+
+		assert.True(t, found, "relation %d has no kept member", r.ID)
+	}
+}
+
+func TestSpatialFilterCompleteWays(t *testing.T) {
+	allNodes, _, _ := decodeSampleAt(t)
+	simpleNodes, simpleWays, simpleRelations := decodeSampleAt(t, WithSpatialFilter(sampleArea, SimpleStrategy))
+	nodes, ways, relations := decodeSampleAt(t, WithSpatialFilter(sampleArea, CompleteWaysStrategy))
+
+	assert.Equal(t, keys(simpleWays), keys(ways))
+	assert.Equal(t, keys(simpleRelations), keys(relations))
+	assert.Greater(t, len(nodes), len(simpleNodes))
+
+	for _, w := range ways {
+		for _, id := range w.NodeIDs {
+			if _, exists := allNodes[id]; exists {
+				assert.Contains(t, nodes, id, "way %d is missing node %d", w.ID, id)
+			}
+		}
+	}
+}
+
+func TestSpatialFilterSmart(t *testing.T) {
+	nodes, ways, relations := decodeSampleAt(t, WithSpatialFilter(sampleArea, SmartStrategy))
+	completeNodes, completeWays, _ := decodeSampleAt(t, WithSpatialFilter(sampleArea, CompleteWaysStrategy))
+
+	assert.GreaterOrEqual(t, len(nodes), len(completeNodes))
+	assert.GreaterOrEqual(t, len(ways), len(completeWays))
+
+	for _, r := range relations {
+		if r.Tags["type"] != "multipolygon" {
+			continue
+		}
+
+		for _, m := range r.Members {
+			if m.Type == model.WAY {
+				assert.Contains(t, ways, m.ID, "multipolygon %d is missing way %d", r.ID, m.ID)
+			}
+		}
+	}
+}
+
+func TestSpatialFilterHonorsEntityTypes(t *testing.T) {
+	nodes, ways, relations := decodeSampleAt(t,
+		WithSpatialFilter(sampleArea, SimpleStrategy),
+		WithEntityTypes(model.WAY))
+
+	assert.Empty(t, nodes)
+	assert.NotEmpty(t, ways)
+	assert.Empty(t, relations)
+}
+
+func TestNewDecoderRejectsMultiPassStrategies(t *testing.T) {
+	in, err := os.Open("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer in.Close()
+
+	_, err = NewDecoder(context.Background(), in, WithSpatialFilter(sampleArea, CompleteWaysStrategy))
+	if !errors.Is(err, ErrUnsupportedSpatialStrategy) {
+		t.Fatalf("expected ErrUnsupportedSpatialStrategy, got: %v", err)
+	}
+}
+
+func TestParseSpatialStrategy(t *testing.T) {
+	for _, s := range []SpatialStrategy{SimpleStrategy, CompleteWaysStrategy, SmartStrategy} {
+		parsed, err := ParseSpatialStrategy(s.String())
+		require.NoError(t, err)
+		assert.Equal(t, s, parsed)
+	}
+
+	_, err := ParseSpatialStrategy("clever")
+	assert.ErrorIs(t, err, ErrUnsupportedSpatialStrategy)
+}
+
+func decodeSampleAt(t *testing.T, opts ...DecoderOption) (
+	map[model.ID]*model.Node,
+	map[model.ID]*model.Way,
+	map[model.ID]*model.Relation,
+) {
+	t.Helper()
+
+	in, err := os.Open("testdata/sample.osm.pbf")
+	require.NoError(t, err)
+	defer in.Close()
+
+	fi, err := in.Stat()
+	require.NoError(t, err)
+
+	dec, err := NewDecoderAt(context.Background(), in, fi.Size(), opts...)
+	require.NoError(t, err)
+	defer dec.Close()
+
+	return decodeByType(t, dec)
+}
+
+func decodeByType(t *testing.T, dec *Decoder) (
+	map[model.ID]*model.Node,
+	map[model.ID]*model.Way,
+	map[model.ID]*model.Relation,
+) {
+	t.Helper()
+
+	nodes := map[model.ID]*model.Node{}
+	ways := map[model.ID]*model.Way{}
+	relations := map[model.ID]*model.Relation{}
+
+	for {
+		entities, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return nodes, ways, relations
+		}
+		require.NoError(t, err)
+
+		for _, e := range entities {
+			switch e := e.(type) {
+			case *model.Node:
+				nodes[e.ID] = e
+			case *model.Way:
+				ways[e.ID] = e
+			case *model.Relation:
+				relations[e.ID] = e
+			}
+		}
+	}
+}
+
+func referencesAny(nodes map[model.ID]*model.Node, ids []model.ID) bool {
+	for _, id := range ids {
+		if _, ok := nodes[id]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func keys[V any](m map[model.ID]V) map[model.ID]bool {
+	ks := make(map[model.ID]bool, len(m))
+	for k := range m {
+		ks[k] = true
+	}
+
+	return ks
+}
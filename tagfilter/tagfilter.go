@@ -0,0 +1,408 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package tagfilter compiles tag filter expressions into predicates over the
+tags of OpenStreetMap entities.
+
+An expression is made up of terms combined with and, or, not and
+parentheses; not binds tighter than and, which binds tighter than or.
+A term is one of
+
+	key              the entity has the tag key
+	key=v1,v2        the entity has the tag key with one of the values
+	key=*            the entity has the tag key, with any value
+	key!=v1,v2       the entity does not have the tag key with one of the values
+
+Keys and values that contain spaces or any of the characters =!,()" can be
+double-quoted, in which case a backslash escapes the following character.
+For example:
+
+	highway=primary,secondary and not access=private
+*/
+package tagfilter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrSyntax is returned when an expression cannot be parsed.
+var ErrSyntax = errors.New("tag filter syntax error")
+
+// Lookup returns the value of the tag key and whether the tag is present.
+type Lookup func(key string) (value string, ok bool)
+
+// Expr is a compiled tag filter expression.  An Expr is immutable and safe
+// for concurrent use.
+type Expr struct {
+	src  string
+	root node
+	keys []string
+}
+
+// Parse compiles the tag filter expression src.
+func Parse(src string) (*Expr, error) {
+	p := &parser{lex: lexer{src: src}}
+	p.next()
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != eofToken {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+
+	keys := make([]string, 0, len(p.keys))
+	for k := range p.keys {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	return &Expr{src: src, root: root, keys: keys}, nil
+}
+
+// MustParse is like Parse but panics if the expression cannot be parsed.
+func MustParse(src string) *Expr {
+	e, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+
+	return e
+}
+
+// Match reports whether tags satisfy the expression.
+func (e *Expr) Match(tags map[string]string) bool {
+	return e.root.eval(func(key string) (string, bool) {
+		v, ok := tags[key]
+
+		return v, ok
+	})
+}
+
+// MatchLookup reports whether the tags, accessed through lookup, satisfy the
+// expression.  Only the keys returned by Keys are looked up.
+func (e *Expr) MatchLookup(lookup Lookup) bool {
+	return e.root.eval(lookup)
+}
+
+// Keys returns, in sorted order, the tag keys referenced by the expression.
+func (e *Expr) Keys() []string {
+	return e.keys
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+type node interface {
+	eval(lookup Lookup) bool
+}
+
+type hasKey struct {
+	key string
+}
+
+func (n hasKey) eval(lookup Lookup) bool {
+	_, ok := lookup(n.key)
+
+	return ok
+}
+
+type valueIn struct {
+	key    string
+	values []string
+}
+
+func (n valueIn) eval(lookup Lookup) bool {
+	v, ok := lookup(n.key)
+
+	return ok && slices.Contains(n.values, v)
+}
+
+type notNode struct {
+	n node
+}
+
+func (n notNode) eval(lookup Lookup) bool {
+	return !n.n.eval(lookup)
+}
+
+type andNode struct {
+	l, r node
+}
+
+func (n andNode) eval(lookup Lookup) bool {
+	return n.l.eval(lookup) && n.r.eval(lookup)
+}
+
+type orNode struct {
+	l, r node
+}
+
+func (n orNode) eval(lookup Lookup) bool {
+	return n.l.eval(lookup) || n.r.eval(lookup)
+}
+
+type parser struct {
+	lex  lexer
+	tok  token
+	keys map[string]struct{}
+}
+
+func (p *parser) next() {
+	p.tok = p.lex.next()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrSyntax, p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) isKeyword(kw string) bool {
+	return p.tok.kind == wordToken && p.tok.text == kw
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("or") {
+		p.next()
+
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		l = orNode{l, r}
+	}
+
+	return l, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("and") {
+		p.next()
+
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		l = andNode{l, r}
+	}
+
+	return l, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch {
+	case p.isKeyword("not"):
+		p.next()
+
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{n}, nil
+	case p.tok.kind == lparenToken:
+		p.next()
+
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind != rparenToken {
+			return nil, p.errorf("expected ')'")
+		}
+
+		p.next()
+
+		return n, nil
+	default:
+		return p.parseTerm()
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	if !p.isText() || p.isKeyword("and") || p.isKeyword("or") {
+		return nil, p.errorf("expected tag key")
+	}
+
+	key := p.tok.text
+	if p.keys == nil {
+		p.keys = map[string]struct{}{}
+	}
+
+	p.keys[key] = struct{}{}
+	p.next()
+
+	negate := p.tok.kind == neqToken
+	if p.tok.kind != eqToken && !negate {
+		return hasKey{key}, nil
+	}
+
+	p.next()
+
+	var values []string
+
+	for {
+		if !p.isText() {
+			return nil, p.errorf("expected tag value")
+		}
+
+		values = append(values, p.tok.text)
+		p.next()
+
+		if p.tok.kind != commaToken {
+			break
+		}
+
+		p.next()
+	}
+
+	var n node = valueIn{key, values}
+	if slices.Contains(values, "*") {
+		n = hasKey{key}
+	}
+
+	if negate {
+		n = notNode{n}
+	}
+
+	return n, nil
+}
+
+func (p *parser) isText() bool {
+	return p.tok.kind == wordToken || p.tok.kind == quotedToken
+}
+
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota
+	wordToken
+	quotedToken
+	eqToken
+	neqToken
+	commaToken
+	lparenToken
+	rparenToken
+	errorToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+const special = "=!,()\""
+
+func (l *lexer) next() token {
+	for l.pos < len(l.src) && isSpace(l.src[l.pos]) {
+		l.pos++
+	}
+
+	start := l.pos
+	if start == len(l.src) {
+		return token{kind: eofToken, pos: start}
+	}
+
+	switch c := l.src[start]; c {
+	case '=':
+		l.pos++
+
+		return token{kind: eqToken, text: "=", pos: start}
+	case '!':
+		if strings.HasPrefix(l.src[start:], "!=") {
+			l.pos += 2
+
+			return token{kind: neqToken, text: "!=", pos: start}
+		}
+
+		l.pos++
+
+		return token{kind: errorToken, text: "!", pos: start}
+	case ',':
+		l.pos++
+
+		return token{kind: commaToken, text: ",", pos: start}
+	case '(':
+		l.pos++
+
+		return token{kind: lparenToken, text: "(", pos: start}
+	case ')':
+		l.pos++
+
+		return token{kind: rparenToken, text: ")", pos: start}
+	case '"':
+		return l.quoted()
+	default:
+		for l.pos < len(l.src) && !isSpace(l.src[l.pos]) && !strings.ContainsRune(special, rune(l.src[l.pos])) {
+			l.pos++
+		}
+
+		return token{kind: wordToken, text: l.src[start:l.pos], pos: start}
+	}
+}
+
+func (l *lexer) quoted() token {
+	start := l.pos
+	l.pos++
+
+	var b strings.Builder
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		l.pos++
+
+		switch {
+		case c == '"':
+			return token{kind: quotedToken, text: b.String(), pos: start}
+		case c == '\\' && l.pos < len(l.src):
+			b.WriteByte(l.src[l.pos])
+			l.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return token{kind: errorToken, text: l.src[start:], pos: start}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
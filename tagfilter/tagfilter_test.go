@@ -0,0 +1,108 @@
+// Copyright 2025 the original author or authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagfilter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"m4o.io/pbf/v2/tagfilter"
+)
+
+func TestMatch(t *testing.T) {
+	primary := map[string]string{"highway": "primary"}
+	privatePrimary := map[string]string{"highway": "primary", "access": "private"}
+	residential := map[string]string{"highway": "residential"}
+	shop := map[string]string{"shop": "bakery", "name": "Brot & Butter"}
+	untagged := map[string]string{}
+
+	test_cases := []struct {
+		expr    string
+		matches []map[string]string
+		misses  []map[string]string
+	}{
+		{"highway", []map[string]string{primary, residential}, []map[string]string{shop, untagged}},
+		{"highway=*", []map[string]string{primary, residential}, []map[string]string{shop}},
+		{"highway=primary,secondary", []map[string]string{primary, privatePrimary}, []map[string]string{residential}},
+		{
+			"highway=primary,secondary and not access=private",
+			[]map[string]string{primary},
+			[]map[string]string{privatePrimary, residential},
+		},
+		{"access!=private", []map[string]string{primary, untagged}, []map[string]string{privatePrimary}},
+		{"shop or highway=residential", []map[string]string{shop, residential}, []map[string]string{primary}},
+		{"not (shop or highway)", []map[string]string{untagged}, []map[string]string{shop, primary}},
+		{"highway or shop and access", []map[string]string{primary}, []map[string]string{shop}},
+		{`name="Brot & Butter"`, []map[string]string{shop}, []map[string]string{primary}},
+		{`"name"="Brot \& Butter"`, []map[string]string{shop}, []map[string]string{primary}},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := tagfilter.Parse(tc.expr)
+			require.NoError(t, err)
+
+			for _, tags := range tc.matches {
+				assert.True(t, e.Match(tags), "%v", tags)
+			}
+
+			for _, tags := range tc.misses {
+				assert.False(t, e.Match(tags), "%v", tags)
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	e := tagfilter.MustParse("highway=primary and not (access=private or highway=track)")
+
+	assert.Equal(t, []string{"access", "highway"}, e.Keys())
+}
+
+func TestMatchLookupOnlyLooksUpKeys(t *testing.T) {
+	e := tagfilter.MustParse("highway and not access=private")
+
+	var looked []string
+
+	matched := e.MatchLookup(func(key string) (string, bool) {
+		looked = append(looked, key)
+
+		return "primary", key == "highway"
+	})
+
+	assert.True(t, matched)
+	assert.Subset(t, e.Keys(), looked)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"highway=",
+		"highway and",
+		"(highway",
+		"highway)",
+		"and",
+		"highway=primary,",
+		`name="unterminated`,
+		"highway ! access",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := tagfilter.Parse(expr)
+			assert.ErrorIs(t, err, tagfilter.ErrSyntax)
+		})
+	}
+}